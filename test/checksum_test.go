@@ -0,0 +1,94 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/adler32"
+	"io/ioutil"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+)
+
+// adlerChecksummer is custom core.Checksummer
+type adlerChecksummer struct {
+	verified int
+}
+
+func (*adlerChecksummer) Get(p []byte) uint32 { return adler32.Checksum(p) }
+func (*adlerChecksummer) Type() string        { return "adler32" }
+
+func (c *adlerChecksummer) Verify(p []byte, expected uint32) error {
+	c.verified++
+	if actual := c.Get(p); actual != expected {
+		return fmt.Errorf("invalid %s checksum, got %d, expected %d", c.Type(), actual, expected)
+	}
+	return nil
+}
+
+func TestTfRecordChecksumPolicy(t *testing.T) {
+	data, size := prepareTFRecord(t, 3)
+	// corrupt "bytesstring" value in the payload of record 1 and checksum of length header of record 2
+	payloadIdx := int64(bytes.Index(data[size:2*size], []byte("bytesstring")))
+	tassert.Fatalf(t, payloadIdx > 0, "expected to find bytes feature in the payload")
+	data[size+payloadIdx] ^= 0xff
+	data[2*size+10] ^= 0xff
+
+	read := func(policy core.ChecksumPolicy) (int, error) {
+		examples, err := core.NewTFRecordReader(bytes.NewReader(data), core.TFRecordOptions{Checksum: policy}).ReadAllExamples()
+		return len(examples), err
+	}
+
+	_, err := read(core.ChecksumFull)
+	var payloadErr *core.PayloadChecksumError
+	tassert.Errorf(t, errors.As(err, &payloadErr) && payloadErr.Index == 1, "expected PayloadChecksumError of record 1, got %v", err)
+
+	_, err = read(core.ChecksumHeader)
+	var headerErr *core.HeaderChecksumError
+	tassert.Errorf(t, errors.As(err, &headerErr) && headerErr.Index == 2, "expected HeaderChecksumError of record 2, got %v", err)
+
+	cnt, err := read(core.ChecksumNone)
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, cnt == 3, "expected 3 examples, got %d", cnt)
+}
+
+func TestTfRecordCustomChecksummer(t *testing.T) {
+	var (
+		buf         = bytes.NewBuffer(nil)
+		indexBuf    = bytes.NewBuffer(nil)
+		checksummer = &adlerChecksummer{}
+		opts        = core.TFRecordOptions{Checksummer: checksummer}
+		examples    = prepareExamples(5)
+	)
+	w := core.NewTFRecordWriter(buf, core.TFRecordOptions{Checksummer: checksummer, Index: indexBuf})
+	for _, ex := range examples {
+		_, err := w.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+	}
+
+	_, err := core.NewTFRecordReader(bytes.NewReader(buf.Bytes())).ReadAllExamples()
+	tassert.Errorf(t, err != nil, "expected default checksummer to reject adler32 checksums")
+
+	readBack, err := core.NewTFRecordReader(bytes.NewReader(buf.Bytes()), opts).ReadAllExamples()
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, len(readBack) == len(examples), "expected %d examples, got %d", len(examples), len(readBack))
+	tassert.Errorf(t, checksummer.verified == 2*len(examples), "expected %d verifications, got %d", 2*len(examples), checksummer.verified)
+
+	// index has to be built with the same checksums policy as TFRecord is read with
+	err = core.BuildTFRecordIndex(bytes.NewReader(buf.Bytes()), ioutil.Discard)
+	tassert.Errorf(t, err != nil, "expected default checksummer to reject adler32 checksums when building index")
+	for _, o := range []core.TFRecordOptions{opts, {Checksum: core.ChecksumNone}} {
+		built := bytes.NewBuffer(nil)
+		tassert.CheckFatal(t, core.BuildTFRecordIndex(bytes.NewReader(buf.Bytes()), built, o))
+		tassert.Errorf(t, bytes.Equal(built.Bytes(), indexBuf.Bytes()), "expected built index to equal written one")
+	}
+
+	index, err := core.ReadTFRecordIndex(indexBuf)
+	tassert.CheckFatal(t, err)
+	_, err = core.NewTFRecordRandomReader(bytes.NewReader(buf.Bytes()), index, opts).ReadExampleAt(3)
+	tassert.CheckFatal(t, err)
+}
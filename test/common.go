@@ -4,6 +4,8 @@
 package test
 
 import (
+	"archive/tar"
+	"bytes"
 	"encoding/binary"
 	"io"
 	"sync"
@@ -22,6 +24,12 @@ type (
 	testSamplesReader struct {
 		readCnt, size int
 	}
+
+	// tarFile is a single file of TAR archive created by prepareTar
+	tarFile struct {
+		name string
+		body []byte
+	}
 )
 
 func (t *testTFExamplesReader) Read() (*core.TFExample, error) {
@@ -48,3 +56,19 @@ func (t *testSamplesReader) Read() (core.Sample, error) {
 	t.readCnt++
 	return sample, nil
 }
+
+// prepareTar creates in-memory TAR archive with files in given order
+func prepareTar(files []tarFile) (*bytes.Buffer, error) {
+	buf := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(buf)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.body)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.body); err != nil {
+			return nil, err
+		}
+	}
+	return buf, tw.Close()
+}
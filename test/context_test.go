@@ -0,0 +1,139 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	"github.com/NVIDIA/go-tfdata/tfdata/pipeline"
+	"github.com/NVIDIA/go-tfdata/tfdata/transform"
+)
+
+// waitGoroutines waits until number of goroutines drops to at most n
+func waitGoroutines(n int) bool {
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		if runtime.NumGoroutine() <= n {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestPipelineDoContextCanceled(t *testing.T) {
+	const samplesCnt = 500 // more than greedy reader buffers, so its goroutine is blocked when canceled
+	files := make([]tarFile, 0, samplesCnt)
+	for i := 0; i < samplesCnt; i++ {
+		files = append(files, tarFile{fmt.Sprintf("sample%04d.cls", i), []byte{byte(i)}})
+	}
+
+	for _, numWorkers := range [][]int{nil, {4}} {
+		source, err := prepareTar(files) // not io.Seeker, so TAR is read in the background
+		tassert.CheckFatal(t, err)
+		goroutines := runtime.NumGoroutine()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var read int32
+		p := pipeline.NewPipeline().FromTar(source).TransformSamples(transform.SampleF(func(s core.Sample) core.Sample {
+			if atomic.AddInt32(&read, 1) == 10 {
+				cancel()
+			}
+			return s
+		}))
+		err = p.SampleToTFExample().ToTFRecord(bytes.NewBuffer(nil), numWorkers...).DoContext(ctx)
+		tassert.Fatalf(t, err == context.Canceled, "expected context.Canceled, got %v", err)
+		tassert.Errorf(t, waitGoroutines(goroutines), "expected goroutines to finish, %d left, had %d before",
+			runtime.NumGoroutine(), goroutines)
+	}
+}
+
+func TestPipelineDoContextTimeout(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		ch := core.NewSampleChannel(1) // never written, so the pipeline is stuck until the timeout
+		defer ch.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		p := pipeline.NewPipeline().WithTarStage(func() (core.SampleReader, error) { return ch, nil })
+		if ordered {
+			p.Ordered(2)
+		}
+		err := p.SampleToTFExample().ToTFRecord(bytes.NewBuffer(nil)).DoContext(ctx)
+		cancel()
+		tassert.Fatalf(t, err == context.DeadlineExceeded, "expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestPipelineDoContextCanceledWriting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shards")
+	tassert.CheckFatal(t, err)
+	defer os.RemoveAll(dir)
+
+	writers := map[string]func(p *pipeline.DefaultPipeline) *pipeline.DefaultPipeline{
+		"single": func(p *pipeline.DefaultPipeline) *pipeline.DefaultPipeline {
+			return p.ToTFRecord(bytes.NewBuffer(nil), 4)
+		},
+		"shards": func(p *pipeline.DefaultPipeline) *pipeline.DefaultPipeline {
+			w, err := core.NewTFRecordShardWriter(core.TFRecordShardOptions{Pattern: filepath.Join(dir, "%d-of-%d.tfrecord")})
+			tassert.CheckFatal(t, err)
+			return p.ToTFRecordShards(w, 4)
+		},
+	}
+	for name, toTFRecord := range writers {
+		t.Run(name, func(t *testing.T) {
+			source, err := prepareTar([]tarFile{{"sample.cls", []byte{1}}})
+			tassert.CheckFatal(t, err)
+
+			// TFExamples are read from the channel, which is never closed, so only ctx passed to the write stage
+			// can stop writing
+			ch := core.NewTFExampleChannel(10)
+			defer ch.Close()
+			for _, ex := range prepareExamples(5) {
+				tassert.CheckFatal(t, ch.Write(ex))
+			}
+			p := pipeline.NewPipeline().FromTar(source).SampleToTFExample()
+			p.WithTFExamplesStage(func(core.TFExampleReader) core.TFExampleReader { return ch })
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			errCh := make(chan error, 1)
+			go func() { errCh <- toTFRecord(p).DoContext(ctx) }()
+			select {
+			case err := <-errCh:
+				tassert.Fatalf(t, err == context.DeadlineExceeded, "expected context.DeadlineExceeded, got %v", err)
+			case <-time.After(10 * time.Second):
+				t.Fatal("writing hasn't been canceled")
+			}
+		})
+	}
+}
+
+func TestTFRecordWriteMessagesAsyncContext(t *testing.T) {
+	var (
+		ch          = core.NewTFExampleChannel(10)
+		w           = core.NewTFRecordWriter(bytes.NewBuffer(nil))
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+	defer ch.Close()
+	for _, ex := range prepareExamples(5) {
+		tassert.CheckFatal(t, ch.Write(ex))
+	}
+
+	// the channel isn't closed, so writing stops only when ctx is canceled
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	err := w.WriteMessagesAsyncContext(ctx, ch, 3)
+	tassert.Fatalf(t, err == context.Canceled, "expected context.Canceled, got %v", err)
+}
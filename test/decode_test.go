@@ -0,0 +1,137 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func TestTfRecordDecodeWorkers(t *testing.T) {
+	const cnt = 1000
+	var (
+		examples = manyFeaturesExamples(cnt)
+		buf      = bytes.NewBuffer(nil)
+	)
+	tassert.CheckFatal(t, core.NewTFRecordWriter(buf).WriteMessages(&tfExamplesSliceReader{examples: examples}))
+
+	r := core.NewTFRecordReader(bytes.NewReader(buf.Bytes()), core.TFRecordOptions{DecodeWorkers: 8})
+	defer r.Close()
+	readBack, err := r.ReadAllExamples()
+	tassert.CheckFatal(t, err)
+	tassert.Fatalf(t, len(readBack) == cnt, "expected %d examples, got %d", cnt, len(readBack))
+	for i := range examples {
+		tassert.Fatalf(t, protobuf.Equal(examples[i], readBack[i]), "example %d differs, order is not preserved", i)
+	}
+}
+
+func TestTfRecordDecodeWorkersInvalidPayload(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w := core.NewTFRecordWriter(buf)
+	for i, ex := range manyFeaturesExamples(20) {
+		_, err := w.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+		if i == 9 {
+			_, err = w.Write([]byte{0xff, 0xff, 0xff})
+			tassert.CheckFatal(t, err)
+		}
+	}
+
+	r := core.NewTFRecordReader(bytes.NewReader(buf.Bytes()), core.TFRecordOptions{DecodeWorkers: 4})
+	_, err := r.ReadAllExamples()
+	var unmarshalErr *core.UnmarshalError
+	tassert.Errorf(t, errors.As(err, &unmarshalErr) && unmarshalErr.Index == 10, "expected UnmarshalError of record 10, got %v", err)
+	r.Close()
+
+	var corruptions []core.TFRecordCorruption
+	r = core.NewTFRecordReader(bytes.NewReader(buf.Bytes()), core.TFRecordOptions{
+		DecodeWorkers: 4,
+		Corruption:    core.CorruptionSkip,
+		OnCorruption:  func(c core.TFRecordCorruption) { corruptions = append(corruptions, c) },
+	})
+	defer r.Close()
+	examples, err := r.ReadAllExamples()
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, len(examples) == 20, "expected 20 examples, got %d", len(examples))
+	tassert.Fatalf(t, len(corruptions) == 1, "expected single corruption, got %d", len(corruptions))
+	tassert.Errorf(t, corruptions[0].Index == 10 && corruptions[0].Length == 19, "unexpected corruption %+v", corruptions[0])
+}
+
+func TestTfRecordDecodeWorkersInterleave(t *testing.T) {
+	sources := prepareSources(t, 50, 70, 30)
+	r := core.NewTFRecordInterleaveReader(sources, core.InterleaveOptions{
+		CycleLength:     2,
+		Parallel:        true,
+		TFRecordOptions: core.TFRecordOptions{DecodeWorkers: 3},
+	})
+	for i := 0; i < 60; i++ {
+		_, err := r.Read()
+		tassert.CheckFatal(t, err)
+	}
+	tassert.CheckFatal(t, r.Close())
+}
+
+func TestTfRecordDecodeWorkersCorruptionOrder(t *testing.T) {
+	var (
+		buf     = bytes.NewBuffer(nil)
+		w       = core.NewTFRecordWriter(buf)
+		offsets []int64 // offsets of records, including ones which aren't TFExamples
+	)
+	for i, ex := range manyFeaturesExamples(20) {
+		offsets = append(offsets, int64(buf.Len()))
+		_, err := w.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+		if i == 4 || i == 14 {
+			offsets = append(offsets, int64(buf.Len()))
+			_, err = w.Write([]byte{0xff, 0xff, 0xff})
+			tassert.CheckFatal(t, err)
+		}
+	}
+
+	// records 5 and 16 can't be unmarshaled, length header of record 9 and payload of record 12 are corrupted
+	// and the last record is truncated, so corruptions are found both when reading and when decoding records
+	data := buf.Bytes()
+	data[offsets[9]] ^= 0xff
+	data[offsets[12]+20] ^= 0xff
+	data = data[:len(data)-10]
+	expected := []struct {
+		offset int64
+		err    interface{}
+	}{
+		{offsets[5], new(*core.UnmarshalError)},
+		{offsets[9], new(*core.HeaderChecksumError)},
+		{offsets[12], new(*core.PayloadChecksumError)},
+		{offsets[16], new(*core.UnmarshalError)},
+		{offsets[21], new(*core.TruncatedRecordError)},
+	}
+
+	var (
+		corruptions []core.TFRecordCorruption
+		reporting   int32
+	)
+	r := core.NewTFRecordReader(bytes.NewReader(data), core.TFRecordOptions{
+		DecodeWorkers: 4,
+		Corruption:    core.CorruptionSkip,
+		OnCorruption: func(c core.TFRecordCorruption) {
+			tassert.Errorf(t, atomic.AddInt32(&reporting, 1) == 1, "OnCorruption called concurrently")
+			corruptions = append(corruptions, c)
+			atomic.AddInt32(&reporting, -1)
+		},
+	})
+	defer r.Close()
+	examples, err := r.ReadAllExamples()
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, len(examples) == 17, "expected 17 examples, got %d", len(examples))
+
+	tassert.Fatalf(t, len(corruptions) == len(expected), "expected %d corruptions, got %d", len(expected), len(corruptions))
+	for i, c := range corruptions {
+		tassert.Errorf(t, c.Offset == expected[i].offset, "expected corruption %d at offset %d, got %d", i, expected[i].offset, c.Offset)
+		tassert.Errorf(t, errors.As(c.Err, expected[i].err), "unexpected reason of corruption %d: %v", i, c.Err)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/archive"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func prepareTFRecord(t *testing.T, cnt int) (data []byte, recordSize int64) {
+	buf := bytes.NewBuffer(nil)
+	w := core.NewTFRecordWriter(buf)
+	for _, ex := range prepareExamples(cnt) {
+		n, err := w.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+		recordSize = int64(n)
+	}
+	return buf.Bytes(), recordSize
+}
+
+func TestTfRecordChecksumErrors(t *testing.T) {
+	data, size := prepareTFRecord(t, 3)
+	data[size+2] ^= 0xff
+	_, err := core.NewTFRecordReader(bytes.NewReader(data)).ReadAllExamples()
+	var headerErr *core.HeaderChecksumError
+	tassert.Fatalf(t, errors.As(err, &headerErr), "expected HeaderChecksumError, got %v", err)
+	tassert.Errorf(t, headerErr.Offset == size && headerErr.Index == 1, "unexpected error position: %v", err)
+	var checksumErr *core.InvalidChecksumError
+	tassert.Errorf(t, errors.As(err, &checksumErr), "expected HeaderChecksumError to wrap InvalidChecksumError")
+
+	data, size = prepareTFRecord(t, 3)
+	data[2*size+20] ^= 0xff
+	_, err = core.NewTFRecordReader(bytes.NewReader(data)).ReadAllExamples()
+	var payloadErr *core.PayloadChecksumError
+	tassert.Fatalf(t, errors.As(err, &payloadErr), "expected PayloadChecksumError, got %v", err)
+	tassert.Errorf(t, payloadErr.Offset == 2*size && payloadErr.Index == 2, "unexpected error position: %v", err)
+}
+
+func TestTfRecordTruncatedError(t *testing.T) {
+	data, size := prepareTFRecord(t, 3)
+	_, err := core.NewTFRecordReader(bytes.NewReader(data[:len(data)-5])).ReadAllExamples()
+	var truncErr *core.TruncatedRecordError
+	tassert.Fatalf(t, errors.As(err, &truncErr), "expected TruncatedRecordError, got %v", err)
+	tassert.Errorf(t, truncErr.Offset == 2*size && truncErr.Index == 2, "unexpected error position: %v", err)
+	tassert.Errorf(t, truncErr.Size == size && truncErr.Read == size-5, "unexpected error sizes: %v", err)
+	tassert.Errorf(t, errors.Is(err, io.ErrUnexpectedEOF), "expected TruncatedRecordError to wrap io.ErrUnexpectedEOF")
+}
+
+func TestTfRecordUnmarshalError(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w := core.NewTFRecordWriter(buf)
+	_, err := w.WriteExample(prepareExamples(1)[0])
+	tassert.CheckFatal(t, err)
+	_, err = w.Write([]byte{0xff, 0xff, 0xff})
+	tassert.CheckFatal(t, err)
+
+	r := core.NewTFRecordReader(bytes.NewReader(buf.Bytes()))
+	_, err = r.Read()
+	tassert.CheckFatal(t, err)
+	_, err = r.Read()
+	var unmarshalErr *core.UnmarshalError
+	tassert.Fatalf(t, errors.As(err, &unmarshalErr), "expected UnmarshalError, got %v", err)
+	tassert.Errorf(t, unmarshalErr.Index == 1, "expected error at record 1, got %d", unmarshalErr.Index)
+}
+
+func TestTarEntryError(t *testing.T) {
+	tarBuf, err := prepareTar([]tarFile{
+		{name: "a.txt", body: []byte("0123456789")},
+		{name: "b.txt", body: bytes.Repeat([]byte("x"), 1000)},
+	})
+	tassert.CheckFatal(t, err)
+
+	// content of b.txt starts after a.txt header, a.txt content and b.txt header
+	const offset = 3 * 512
+	r, err := archive.NewTarReader(bytes.NewBuffer(tarBuf.Bytes()[:offset+100]))
+	tassert.CheckFatal(t, err)
+	_, err = r.Read()
+	var entryErr *archive.TarEntryError
+	tassert.Fatalf(t, errors.As(err, &entryErr), "expected TarEntryError, got %v", err)
+	tassert.Errorf(t, entryErr.Key == "b" && entryErr.Member == "txt", "unexpected entry %s.%s", entryErr.Key, entryErr.Member)
+	tassert.Errorf(t, entryErr.Offset == offset, "expected entry offset %d, got %d", offset, entryErr.Offset)
+}
+
+func TestTarEntryErrorHeaderOffset(t *testing.T) {
+	tarBuf, err := prepareTar([]tarFile{
+		{name: "a.txt", body: []byte("0123456789")},
+		{name: "b.txt", body: bytes.Repeat([]byte("x"), 1000)},
+		{name: "c.txt", body: []byte("c")},
+	})
+	tassert.CheckFatal(t, err)
+
+	// header of c.txt follows a.txt header, a.txt content padded to 512 bytes, b.txt header and
+	// b.txt content padded to 1024 bytes. Corrupting its name breaks the header's checksum.
+	const offset = 5 * 512
+	corrupted := tarBuf.Bytes()
+	corrupted[offset] ^= 0xff
+
+	readers := map[string]func() (core.SampleReader, error){
+		"seek": func() (core.SampleReader, error) { return archive.NewTarReader(bytes.NewReader(corrupted)) },
+		"greedy": func() (core.SampleReader, error) {
+			return archive.NewTarReader(&nonSeekable{bytes.NewBuffer(corrupted)})
+		},
+		"stream": func() (core.SampleReader, error) { return archive.NewTarStreamReader(bytes.NewBuffer(corrupted)), nil },
+	}
+	for name, newReader := range readers {
+		t.Run(name, func(t *testing.T) {
+			r, err := newReader()
+			for err == nil {
+				_, err = r.Read()
+			}
+			var entryErr *archive.TarEntryError
+			tassert.Fatalf(t, errors.As(err, &entryErr), "expected TarEntryError, got %v", err)
+			tassert.Errorf(t, entryErr.Key == "", "expected header error, got entry %s.%s", entryErr.Key, entryErr.Member)
+			tassert.Errorf(t, entryErr.Offset == offset, "expected header offset %d, got %d", offset, entryErr.Offset)
+		})
+	}
+}
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// maskedCRC computes TFRecord checksum of p
+func maskedCRC(p []byte) uint32 {
+	crc := crc32.Checksum(p, crcTable)
+	return ((crc >> 15) | (crc << 17)) + 0xa282ead8
+}
+
+// craftedHeader returns valid TFRecord length header declaring payload of given length
+func craftedHeader(length uint64) []byte {
+	header := make([]byte, 12)
+	binary.LittleEndian.PutUint64(header, length)
+	binary.LittleEndian.PutUint32(header[8:], maskedCRC(header[:8]))
+	return header
+}
+
+func TestTfRecordOversizedError(t *testing.T) {
+	data, size := prepareTFRecord(t, 3)
+	_, err := core.NewTFRecordReader(bytes.NewReader(data), core.TFRecordOptions{MaxRecordSize: size - 17}).ReadAllExamples()
+	var oversizedErr *core.OversizedRecordError
+	tassert.Fatalf(t, errors.As(err, &oversizedErr), "expected OversizedRecordError, got %v", err)
+	tassert.Errorf(t, oversizedErr.Index == 0 && oversizedErr.Length == uint64(size-16), "unexpected error: %v", err)
+
+	_, err = core.NewTFRecordReader(bytes.NewReader(data), core.TFRecordOptions{MaxRecordSize: size - 16}).ReadAllExamples()
+	tassert.CheckFatal(t, err)
+
+	_, err = core.NewTFRecordReader(bytes.NewReader(craftedHeader(1 << 40))).ReadAllExamples()
+	tassert.Fatalf(t, errors.As(err, &oversizedErr), "expected OversizedRecordError, got %v", err)
+}
+
+func TestTfRecordCraftedLength(t *testing.T) {
+	const length = 1 << 30
+	data := append(craftedHeader(length), []byte("short payload")...)
+
+	// size of bytes.Reader is known, so the record is rejected before reading it
+	_, err := core.NewTFRecordReader(bytes.NewReader(data)).ReadAllExamples()
+	var truncErr *core.TruncatedRecordError
+	tassert.Fatalf(t, errors.As(err, &truncErr), "expected TruncatedRecordError, got %v", err)
+	tassert.Errorf(t, truncErr.Size == length+16 && truncErr.Read == int64(len(data)), "unexpected error sizes: %v", err)
+
+	// size of bytes.Buffer is unknown, so the payload is read in chunks
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = core.NewTFRecordReader(bytes.NewBuffer(data)).ReadAllExamples()
+	runtime.ReadMemStats(&after)
+	tassert.Fatalf(t, errors.As(err, &truncErr), "expected TruncatedRecordError, got %v", err)
+	tassert.Errorf(t, truncErr.Read == int64(len(data)), "expected %d bytes to be read, got %d", len(data), truncErr.Read)
+	allocated := after.TotalAlloc - before.TotalAlloc
+	tassert.Errorf(t, allocated < length/16, "expected reader to allocate only for available bytes, allocated %d", allocated)
+}
+
+func TestTfRecordStreamedPayload(t *testing.T) {
+	ex := core.NewTFExample()
+	ex.AddBytes("bytes", bytes.Repeat([]byte{42}, 3<<20))
+	buf := bytes.NewBuffer(nil)
+	_, err := core.NewTFRecordWriter(buf).WriteExample(ex)
+	tassert.CheckFatal(t, err)
+
+	examples, err := core.NewTFRecordReader(bytes.NewBuffer(buf.Bytes())).ReadAllExamples()
+	tassert.CheckFatal(t, err)
+	tassert.Fatalf(t, len(examples) == 1 && protobuf.Equal(examples[0], ex), "expected large example to be read back")
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// Write TFRecord with index, rebuild the index from TFRecord and read records randomly
+func TestTfRecordIndex(t *testing.T) {
+	const cnt = 50
+	var (
+		data, index = bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+		tfExamples  = prepareExamples(cnt)
+	)
+	for i, ex := range tfExamples {
+		ex.AddInt("idx", i)
+	}
+
+	w := core.NewTFRecordWriter(data, core.TFRecordOptions{Index: index})
+	for _, ex := range tfExamples {
+		_, err := w.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+	}
+	tassert.CheckFatal(t, w.Close())
+
+	// the same index should be built from seekable and not seekable readers
+	seekIndex, streamIndex := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	tassert.CheckFatal(t, core.BuildTFRecordIndex(bytes.NewReader(data.Bytes()), seekIndex))
+	tassert.CheckFatal(t, core.BuildTFRecordIndex(bytes.NewBuffer(data.Bytes()), streamIndex))
+	tassert.Errorf(t, bytes.Equal(index.Bytes(), seekIndex.Bytes()), "expected built index to equal written one")
+	tassert.Errorf(t, bytes.Equal(index.Bytes(), streamIndex.Bytes()), "expected built index to equal written one")
+
+	idx, err := core.ReadTFRecordIndex(index)
+	tassert.CheckFatal(t, err)
+	tassert.Fatalf(t, len(idx) == cnt, "expected index to have %d entries, got %d", cnt, len(idx))
+	last := idx[len(idx)-1]
+	tassert.Errorf(t, last.Offset+last.Size == int64(data.Len()), "expected last index entry to end at the end of TFRecord")
+
+	r := core.NewTFRecordRandomReader(bytes.NewReader(data.Bytes()), idx)
+	tassert.Fatalf(t, r.Len() == cnt, "expected random reader to have %d records, got %d", cnt, r.Len())
+	for _, i := range []int{cnt - 1, 0, cnt / 2} {
+		ex, err := r.ReadExampleAt(i)
+		tassert.CheckFatal(t, err)
+		tassert.Errorf(t, protobuf.Equal(ex, tfExamples[i]), "example %d doesn't equal written one", i)
+	}
+
+	examples, err := r.ReadExamplesRange(10, 20)
+	tassert.CheckFatal(t, err)
+	tassert.Fatalf(t, len(examples) == 10, "expected to read 10 examples, got %d", len(examples))
+	for i, ex := range examples {
+		tassert.Errorf(t, protobuf.Equal(ex, tfExamples[10+i]), "example %d doesn't equal written one", 10+i)
+	}
+	// indices out of range are reported as errors
+	for _, i := range []int{-1, cnt} {
+		_, err = r.ReadExampleAt(i)
+		tassert.Errorf(t, errors.Is(err, core.ErrIndexOutOfRange), "expected ErrIndexOutOfRange for %d, got %v", i, err)
+	}
+	for _, rng := range [][2]int{{-1, 5}, {5, 4}, {0, cnt + 1}} {
+		_, err = r.ReadExamplesRange(rng[0], rng[1])
+		tassert.Errorf(t, errors.Is(err, core.ErrIndexOutOfRange), "expected ErrIndexOutOfRange for %v, got %v", rng, err)
+	}
+}
+
+func TestTfRecordIndexMedium(t *testing.T) {
+	const path = "data/tf-train-medium.record"
+
+	f, err := os.Open(path)
+	tassert.CheckFatal(t, err)
+	defer f.Close()
+
+	index := bytes.NewBuffer(nil)
+	tassert.CheckFatal(t, core.BuildTFRecordIndex(f, index))
+	idx, err := core.ReadTFRecordIndex(index)
+	tassert.CheckFatal(t, err)
+	tassert.Fatalf(t, len(idx) == 7, "expected index to have 7 entries, got %d", len(idx))
+
+	data, err := ioutil.ReadFile(path)
+	tassert.CheckFatal(t, err)
+	r := core.NewTFRecordRandomReader(f, idx)
+	examples, err := r.ReadExamplesRange(0, r.Len())
+	tassert.CheckFatal(t, err)
+	expected, err := core.NewTFRecordReader(bytes.NewReader(data)).ReadAllExamples()
+	tassert.CheckFatal(t, err)
+	for i := range expected {
+		tassert.Errorf(t, protobuf.Equal(examples[i], expected[i]), "example %d doesn't equal sequentially read one", i)
+	}
+}
+
+func TestTfRecordIndexTruncated(t *testing.T) {
+	data := bytes.NewBuffer(nil)
+	w := core.NewTFRecordWriter(data)
+	for _, ex := range prepareExamples(3) {
+		_, err := w.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+	}
+
+	truncated := data.Bytes()[:data.Len()-5]
+	err := core.BuildTFRecordIndex(bytes.NewBuffer(truncated), ioutil.Discard)
+	tassert.Fatalf(t, err != nil, "expected error on truncated TFRecord")
+	err = core.BuildTFRecordIndex(bytes.NewReader(truncated), ioutil.Discard)
+	tassert.Fatalf(t, err != nil, "expected error on truncated seekable TFRecord")
+}
+
+func TestTfRecordIndexMalformed(t *testing.T) {
+	for _, malformed := range []string{"100 50\n0 50\n", "0 50\n40 50\n", "-1 50\n", "0 3\n"} {
+		_, err := core.ReadTFRecordIndex(bytes.NewBufferString(malformed))
+		tassert.Errorf(t, err != nil, "expected error for index %q", malformed)
+	}
+
+	data, size := prepareTFRecord(t, 3)
+	idx := core.TFRecordIndex{{Offset: 2 * size, Size: size}, {Offset: 0, Size: size}}
+	_, err := core.NewTFRecordRandomReader(bytes.NewReader(data), idx).ReadExamplesRange(0, 2)
+	tassert.Errorf(t, err != nil, "expected error for index not in order of offsets")
+
+	// records past the end of TFRecord are reported as truncated
+	idx = core.TFRecordIndex{{Offset: 0, Size: size}, {Offset: 3 * size, Size: size}, {Offset: 5 * size, Size: size}}
+	_, err = core.NewTFRecordRandomReader(bytes.NewReader(data), idx).ReadExamplesRange(0, 3)
+	var truncatedErr *core.TruncatedRecordError
+	tassert.Fatalf(t, errors.As(err, &truncatedErr), "expected TruncatedRecordError, got %v", err)
+	tassert.Errorf(t, truncatedErr.Index == 1 && truncatedErr.Read == 0, "unexpected truncated record %+v", truncatedErr)
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/proto"
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func jsonTestExample() *core.TFExample {
+	ex := core.NewTFExample()
+	ex.AddInt("label", 1, -2)
+	ex.AddFloat("bbox", 0.1, 1e-7, 3.5)
+	ex.AddBytes("name", []byte("cat.jpg"))
+	ex.AddBytes("raw", []byte{0xff, 0x00, 0xd8})
+	ex.AddInt64List("no-ints", []int64{})
+	ex.SetFeature("empty", &proto.Feature{})
+	return ex
+}
+
+func TestTFExampleJSON(t *testing.T) {
+	ex := jsonTestExample()
+	b, err := json.Marshal(ex)
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, strings.Contains(string(b), `"name":{"bytes":["cat.jpg"]}`), "expected UTF-8 bytes as string, got %s", b)
+	tassert.Errorf(t, strings.Contains(string(b), `"raw":{"bytes":[{"base64":"/wDY"}]}`), "expected base64 bytes, got %s", b)
+	tassert.Errorf(t, strings.Contains(string(b), `"bbox":{"float":[0.1,1e-07,3.5]}`), "unexpected floats in %s", b)
+
+	decoded := core.NewTFExample()
+	tassert.CheckFatal(t, json.Unmarshal(b, decoded))
+	tassert.Errorf(t, protobuf.Equal(&ex.Example, &decoded.Example), "expected decoded TFExample to equal original, got %s", b)
+
+	special := core.NewTFExample()
+	special.AddFloat("special", float32(math.NaN()), float32(math.Inf(1)), float32(math.Inf(-1)))
+	b, err = json.Marshal(special)
+	tassert.CheckFatal(t, err)
+	tassert.CheckFatal(t, json.Unmarshal(b, decoded))
+	values := decoded.GetFloatList("special")
+	tassert.Errorf(t, len(values) == 3 && math.IsNaN(float64(values[0])) && math.IsInf(float64(values[1]), 1) &&
+		math.IsInf(float64(values[2]), -1), "unexpected special floats %v from %s", values, b)
+
+	for _, invalid := range []string{
+		`{"features": {"x": {"int64": [1], "float": [1]}}}`,
+		`{"features": {"x": {"string": ["a"]}}}`,
+		`{"features": {"x": {"bytes": [{"base64": "!"}]}}}`,
+		`{"features": {"x": {"float": ["one"]}}}`,
+	} {
+		tassert.Errorf(t, json.Unmarshal([]byte(invalid), decoded) != nil, "expected error for %s", invalid)
+	}
+}
+
+func TestTFExampleTextProto(t *testing.T) {
+	ex := jsonTestExample()
+	b, err := ex.MarshalTextProto()
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, strings.Contains(string(b), `value: "\377\000\330"`), "expected octal escapes in pbtxt %s", b)
+
+	decoded := core.NewTFExample()
+	tassert.CheckFatal(t, decoded.UnmarshalTextProto(b))
+	tassert.Errorf(t, protobuf.Equal(&ex.Example, &decoded.Example), "expected decoded TFExample to equal original, got %s", b)
+
+	simple := core.NewTFExample()
+	simple.AddInt("cls", 7)
+	simple.AddFloat("special", float32(math.NaN()), float32(math.Inf(-1)))
+	b, err = simple.MarshalTextProto()
+	tassert.CheckFatal(t, err)
+	expected := `features {
+  feature {
+    key: "cls"
+    value {
+      int64_list {
+        value: 7
+      }
+    }
+  }
+  feature {
+    key: "special"
+    value {
+      float_list {
+        value: nan
+        value: -inf
+      }
+    }
+  }
+}
+`
+	tassert.Errorf(t, string(b) == expected, "expected pbtxt\n%s\ngot\n%s", expected, b)
+	tassert.CheckFatal(t, decoded.UnmarshalTextProto(b))
+	values := decoded.GetFloatList("special")
+	tassert.Errorf(t, len(values) == 2 && math.IsNaN(float64(values[0])) && math.IsInf(float64(values[1]), -1),
+		"unexpected special floats %v", values)
+
+	// format written by TensorFlow
+	tassert.CheckFatal(t, decoded.UnmarshalTextProto([]byte(`features { feature { key: "cls" value { int64_list { value: 7 } } } }`)))
+	tassert.Errorf(t, decoded.GetInt64("cls") == 7, "expected cls 7")
+	decoded = core.NewTFExample()
+	tassert.CheckFatal(t, decoded.UnmarshalTextProto(nil))
+	decoded.AddInt("cls", 1) // features map has to be usable after unmarshalling empty TFExample
+}
+
+func TestTFExampleJSONL(t *testing.T) {
+	const cnt = 10
+	examples := prepareExamples(cnt)
+	buf := bytes.NewBuffer(nil)
+	w := core.NewJSONLWriter(buf)
+	for _, ex := range examples {
+		tassert.CheckFatal(t, w.Write(ex))
+	}
+	w.Close()
+	tassert.Errorf(t, strings.Count(buf.String(), "\n") == cnt, "expected %d lines", cnt)
+
+	r := core.NewJSONLReader(buf)
+	for i := 0; i < cnt; i++ {
+		ex, err := r.Read()
+		tassert.CheckFatal(t, err)
+		tassert.Errorf(t, protobuf.Equal(&ex.Example, &examples[i].Example), "TFExample %d doesn't equal written one", i)
+	}
+	_, err := r.Read()
+	tassert.Fatalf(t, err == io.EOF, "expected EOF, got %v", err)
+
+	r = core.NewJSONLReader(strings.NewReader(`{"features": {}}` + "\n" + `{"features": `))
+	_, err = r.Read()
+	tassert.CheckFatal(t, err)
+	_, err = r.Read()
+	tassert.Errorf(t, err != nil && err != io.EOF, "expected error on truncated line, got %v", err)
+
+	for _, invalid := range []string{
+		`{"features": {}}{"features": {}}`,
+		"{\"features\":\n{}}",
+	} {
+		_, err = core.NewJSONLReader(strings.NewReader(invalid)).Read()
+		tassert.Errorf(t, err != nil && err != io.EOF, "expected error for %q, got %v", invalid, err)
+	}
+
+	// lines are longer than the default limit of bufio.Scanner, as in JSON Lines with images
+	big := core.NewTFExample()
+	big.AddBytes("image", bytes.Repeat([]byte{0xff}, 1<<20))
+	buf.Reset()
+	w = core.NewJSONLWriter(buf)
+	tassert.CheckFatal(t, w.Write(big))
+	tassert.CheckFatal(t, w.Write(big))
+	r = core.NewJSONLReader(bytes.NewBuffer(append([]byte("\n"), buf.Bytes()...)))
+	for i := 0; i < 2; i++ {
+		ex, err := r.Read()
+		tassert.CheckFatal(t, err)
+		tassert.Errorf(t, protobuf.Equal(&ex.Example, &big.Example), "TFExample %d doesn't equal written one", i)
+	}
+	_, err = r.Read()
+	tassert.Fatalf(t, err == io.EOF, "expected EOF, got %v", err)
+}
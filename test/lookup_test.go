@@ -0,0 +1,63 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/proto"
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+)
+
+func TestTFExampleLookup(t *testing.T) {
+	ex := core.NewTFExample()
+	ex.AddInt("label", 3)
+	ex.AddInt("ints", 1, 2, 3)
+	ex.AddInt64List("no-ints", nil)
+	ex.AddFloat("score", 0.5)
+	ex.AddBytes("tags", []byte("a"), []byte("b"))
+	ex.AddBytes("name", []byte("sample"))
+	ex.SetFeature("empty", &proto.Feature{})
+
+	label, err := ex.LookupInt64("label")
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, label == 3, "expected label 3, got %d", label)
+	score, err := ex.LookupFloat("score")
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, score == 0.5, "expected score 0.5, got %f", score)
+	tags, err := ex.LookupBytesList("tags")
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, len(tags) == 2 && string(tags[1]) == "b", "unexpected tags %q", tags)
+	name, err := ex.LookupString("name")
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, name == "sample", "expected name sample, got %s", name)
+	empty, err := ex.LookupFloatList("empty")
+	tassert.Errorf(t, err == nil && len(empty) == 0, "expected feature without kind to be empty list, got %v, %v", empty, err)
+
+	missing := &core.MissingFeatureError{}
+	_, err = ex.LookupInt64("missing")
+	tassert.Errorf(t, errors.As(err, &missing) && missing.Feature == "missing", "expected MissingFeatureError, got %v", err)
+	_, err = ex.LookupBytesList("missing")
+	tassert.Errorf(t, errors.As(err, &missing), "expected MissingFeatureError, got %v", err)
+
+	kind := &core.FeatureKindError{}
+	_, err = ex.LookupFloat("label")
+	tassert.Errorf(t, errors.As(err, &kind) && kind.Expected == core.FeatureKindFloat && kind.Actual == core.FeatureKindInt64,
+		"expected FeatureKindError, got %v", err)
+	_, err = ex.LookupImage("score")
+	tassert.Errorf(t, errors.As(err, &kind), "expected FeatureKindError, got %v", err)
+
+	cardinality := &core.FeatureCardinalityError{}
+	_, err = ex.LookupInt64("ints")
+	tassert.Errorf(t, errors.As(err, &cardinality) && cardinality.Actual == 3, "expected FeatureCardinalityError, got %v", err)
+	_, err = ex.LookupInt64("no-ints")
+	tassert.Errorf(t, errors.As(err, &cardinality) && cardinality.Actual == 0, "expected FeatureCardinalityError, got %v", err)
+	_, err = ex.LookupBytes("tags")
+	tassert.Errorf(t, errors.As(err, &cardinality) && cardinality.Actual == 2, "expected FeatureCardinalityError, got %v", err)
+
+	// TFExample without features at all, as unmarshalled from an empty record
+	_, err = (&core.TFExample{}).LookupBytes("name")
+	tassert.Errorf(t, errors.As(err, &missing), "expected MissingFeatureError, got %v", err)
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+)
+
+type (
+	testBBox struct {
+		XMin float32 `tfexample:"xmin"`
+		XMax float32 `tfexample:"xmax"`
+	}
+
+	testMeta struct {
+		Source string `tfexample:"source"`
+	}
+
+	testRecord struct {
+		testMeta
+		Label    int            `tfexample:"label"`
+		Class    uint8          `tfexample:"class,float"`
+		Valid    bool           `tfexample:"valid"`
+		Score    float64        `tfexample:"score,omitempty"`
+		Name     string         `tfexample:"name"`
+		Raw      []byte         `tfexample:"raw"`
+		Tags     []string       `tfexample:"tags"`
+		Ids      []int16        `tfexample:"ids"`
+		Image    image.Image    `tfexample:"image,omitempty"`
+		BBox     testBBox       `tfexample:"image/bbox"`
+		Crop     *testBBox      `tfexample:"image/crop"`
+		Weight   *float32       `tfexample:"weight"`
+		Untagged int64          // stored as Untagged
+		Cached   map[string]int `tfexample:"-"`
+		internal int
+	}
+)
+
+func TestMarshalExample(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(1, 1, color.NRGBA{R: 255, A: 255})
+	weight := float32(0.5)
+	record := testRecord{
+		testMeta: testMeta{Source: "camera"},
+		Label:    -3,
+		Class:    7,
+		Valid:    true,
+		Name:     "cat",
+		Raw:      []byte{0xff, 0x00},
+		Tags:     []string{"a", "b"},
+		Ids:      []int16{1, 2, 3},
+		Image:    img,
+		BBox:     testBBox{XMin: 0.1, XMax: 0.9},
+		Weight:   &weight,
+		Untagged: 42,
+		Cached:   map[string]int{"x": 1},
+		internal: 1,
+	}
+
+	ex, err := core.MarshalExample(&record)
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, ex.GetInt64("label") == -3, "expected label -3")
+	tassert.Errorf(t, ex.GetFloat("class") == 7, "expected class stored as float")
+	tassert.Errorf(t, ex.GetInt64("valid") == 1, "expected valid stored as 1")
+	tassert.Errorf(t, !ex.HasFeature("score"), "expected empty score to be omitted")
+	tassert.Errorf(t, ex.HasFeature("source") && ex.HasFeature("Untagged"), "expected embedded and untagged fields")
+	tassert.Errorf(t, ex.GetFloat("image/bbox/xmax") == 0.9, "expected nested bbox with prefix")
+	tassert.Errorf(t, !ex.HasFeature("image/crop/xmin"), "expected nil pointer to be skipped")
+	tassert.Errorf(t, !ex.HasFeature("Cached") && !ex.HasFeature("internal"), "expected ignored fields to be skipped")
+	tassert.Errorf(t, len(ex.Features.Feature) == 13, "expected 13 features, got %d", len(ex.Features.Feature))
+
+	decoded := testRecord{}
+	tassert.CheckFatal(t, core.UnmarshalExample(ex, &decoded))
+	tassert.Errorf(t, decoded.Label == -3 && decoded.Class == 7 && decoded.Valid && decoded.Untagged == 42,
+		"unexpected decoded scalars %+v", decoded)
+	tassert.Errorf(t, decoded.Source == "camera" && decoded.Name == "cat" && string(decoded.Raw) == "\xff\x00",
+		"unexpected decoded bytes %+v", decoded)
+	tassert.Errorf(t, len(decoded.Tags) == 2 && decoded.Tags[1] == "b", "unexpected decoded tags %v", decoded.Tags)
+	tassert.Errorf(t, len(decoded.Ids) == 3 && decoded.Ids[2] == 3, "unexpected decoded ids %v", decoded.Ids)
+	tassert.Errorf(t, decoded.BBox == record.BBox && decoded.Crop == nil, "unexpected decoded bbox %+v %+v", decoded.BBox, decoded.Crop)
+	tassert.Errorf(t, decoded.Weight != nil && *decoded.Weight == 0.5, "unexpected decoded weight")
+	tassert.Fatalf(t, decoded.Image != nil, "expected decoded image")
+	r, _, _, a := decoded.Image.At(1, 1).RGBA()
+	tassert.Errorf(t, decoded.Image.Bounds().Dx() == 2 && r == 0xffff && a == 0xffff, "unexpected decoded image")
+}
+
+func TestUnmarshalExampleErrors(t *testing.T) {
+	ex := core.NewTFExample()
+	ex.AddInt("label", 1, 2)
+	ex.AddFloat("score", 0.5)
+	ex.AddInt("class", 300)
+
+	var v struct {
+		Label int `tfexample:"label"`
+	}
+	cardinality := &core.FeatureCardinalityError{}
+	err := core.UnmarshalExample(ex, &v)
+	tassert.Errorf(t, errors.As(err, &cardinality), "expected FeatureCardinalityError, got %v", err)
+
+	var w struct {
+		Score int `tfexample:"score"`
+	}
+	kind := &core.FeatureKindError{}
+	err = core.UnmarshalExample(ex, &w)
+	tassert.Errorf(t, errors.As(err, &kind), "expected FeatureKindError, got %v", err)
+
+	var c struct {
+		Class uint8 `tfexample:"class"`
+	}
+	tassert.Errorf(t, core.UnmarshalExample(ex, &c) != nil, "expected overflow error")
+
+	var s struct {
+		Score float32 `tfexample:"score,int64"`
+	}
+	_, err = core.MarshalExample(s)
+	tassert.Errorf(t, err != nil, "expected float to be rejected as int64")
+	_, err = core.MarshalExample(struct{ M map[string]int }{})
+	tassert.Errorf(t, err != nil, "expected map to be rejected")
+	tassert.Errorf(t, core.UnmarshalExample(ex, v) != nil, "expected non-pointer to be rejected")
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+)
+
+const (
+	fileEntry   = "file"
+	recordEntry = "record"
+)
+
+// prepareSources creates in-memory TFRecords; i-th of them has sizes[i] TFExamples
+func prepareSources(t *testing.T, sizes ...int) []core.TFRecordSource {
+	sources := make([]core.TFRecordSource, 0, len(sizes))
+	for i, size := range sizes {
+		buf := bytes.NewBuffer(nil)
+		w := core.NewTFRecordWriter(buf)
+		for j := 0; j < size; j++ {
+			ex := core.NewTFExample()
+			ex.AddInt(fileEntry, i)
+			ex.AddInt(recordEntry, j)
+			_, err := w.WriteExample(ex)
+			tassert.CheckFatal(t, err)
+		}
+		b := buf.Bytes()
+		sources = append(sources, func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		})
+	}
+	return sources
+}
+
+func readOrder(t *testing.T, r *core.TFRecordMultiReader) []string {
+	var (
+		ex    *core.TFExample
+		err   error
+		order []string
+	)
+	for ex, err = r.Read(); err == nil; ex, err = r.Read() {
+		order = append(order, fmt.Sprintf("%d:%d", ex.GetInt64(fileEntry), ex.GetInt64(recordEntry)))
+	}
+	tassert.Fatalf(t, err == io.EOF, "expected EOF, got %v", err)
+	tassert.CheckFatal(t, r.Close())
+	return order
+}
+
+func TestTfRecordConcatReader(t *testing.T) {
+	r := core.NewTFRecordConcatReader(prepareSources(t, 2, 0, 3))
+	order := fmt.Sprint(readOrder(t, r))
+	expected := "[0:0 0:1 2:0 2:1 2:2]"
+	tassert.Errorf(t, order == expected, "expected order %s, got %s", expected, order)
+}
+
+func TestTfRecordInterleaveReader(t *testing.T) {
+	// tf.data.Dataset.range(4).interleave(f, cycle_length=2, block_length=2), where
+	// f(i) yields records of i-th source
+	expected := "[0:0 0:1 1:0 0:2 2:0 2:1 3:0 3:1 2:2 3:2 3:3]"
+	for _, parallel := range []bool{false, true} {
+		r := core.NewTFRecordInterleaveReader(prepareSources(t, 3, 1, 3, 4), core.InterleaveOptions{
+			CycleLength: 2,
+			BlockLength: 2,
+			Parallel:    parallel,
+		})
+		order := fmt.Sprint(readOrder(t, r))
+		tassert.Errorf(t, order == expected, "parallel=%v: expected order %s, got %s", parallel, expected, order)
+	}
+}
+
+func TestTfRecordInterleaveReaderEarlyClose(t *testing.T) {
+	r := core.NewTFRecordInterleaveReader(prepareSources(t, 100, 100, 100), core.InterleaveOptions{
+		CycleLength: 3,
+		Parallel:    true,
+	})
+	_, err := r.Read()
+	tassert.CheckFatal(t, err)
+	tassert.CheckFatal(t, r.Close())
+}
+
+func TestTfRecordGlobSources(t *testing.T) {
+	sources, err := core.GlobSources("data/*.record")
+	tassert.CheckFatal(t, err)
+	tassert.Fatalf(t, len(sources) == 2, "expected 2 TFRecord files, got %d", len(sources))
+
+	r := core.NewTFRecordInterleaveReader(sources, core.InterleaveOptions{CycleLength: 2, Parallel: true})
+	defer r.Close()
+	cnt := 0
+	for _, err = r.Read(); err == nil; _, err = r.Read() {
+		cnt++
+	}
+	tassert.Fatalf(t, err == io.EOF, "expected EOF, got %v", err)
+	tassert.Errorf(t, cnt == 8, "expected to read 8 examples, got %d", cnt)
+}
+
+func TestTfRecordInterleaveReaderParallelError(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w := core.NewTFRecordWriter(buf)
+	sizes := make([]int, 0, 3)
+	for _, ex := range prepareExamples(3) {
+		n, err := w.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+		sizes = append(sizes, n)
+	}
+	tassert.CheckFatal(t, w.Close())
+	b := buf.Bytes()
+	b[sizes[0]+sizes[1]-1] ^= 0xff // corrupt payload checksum of the second record
+
+	r := core.NewTFRecordInterleaveReader([]core.TFRecordSource{func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}}, core.InterleaveOptions{Parallel: true})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := r.Read()
+		tassert.CheckError(t, err)
+		// the error has to be returned by all subsequent reads, instead of blocking them
+		for i := 0; i < 3; i++ {
+			_, err = r.Read()
+			tassert.Errorf(t, err != nil && err != io.EOF, "read %d: expected checksum error, got %v", i+2, err)
+		}
+		tassert.CheckError(t, r.Close())
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("TFRecordMultiReader blocked after an error")
+	}
+}
+
+func TestTfRecordInterleaveReaderSourceError(t *testing.T) {
+	options := core.InterleaveOptions{CycleLength: 2}
+	expected := fmt.Sprint(readOrder(t, core.NewTFRecordInterleaveReader(prepareSources(t, 3, 4, 5), options)))
+
+	// the second source fails to open once; it has to be opened again by the next Read instead of being skipped
+	sources := prepareSources(t, 3, 4, 5)
+	source, failed := sources[1], false
+	sources[1] = func() (io.ReadCloser, error) {
+		if !failed {
+			failed = true
+			return nil, errors.New("temporary error")
+		}
+		return source()
+	}
+	r := core.NewTFRecordInterleaveReader(sources, options)
+	var order []string
+	ex, err := r.Read()
+	for ; err == nil; ex, err = r.Read() {
+		order = append(order, fmt.Sprintf("%d:%d", ex.GetInt64(fileEntry), ex.GetInt64(recordEntry)))
+	}
+	tassert.Fatalf(t, err != io.EOF, "expected source error, got EOF")
+	order = append(order, readOrder(t, r)...)
+	tassert.Errorf(t, fmt.Sprint(order) == expected, "expected order %s, got %v", expected, order)
+}
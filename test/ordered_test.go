@@ -0,0 +1,119 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	"github.com/NVIDIA/go-tfdata/tfdata/pipeline"
+	"github.com/NVIDIA/go-tfdata/tfdata/transform"
+)
+
+type (
+	// failingTFExamplesReader returns error after size TFExamples
+	failingTFExamplesReader struct {
+		testTFExamplesReader
+	}
+
+	// tfExamplesSliceReader reads TFExamples from a slice
+	tfExamplesSliceReader struct {
+		examples []*core.TFExample
+	}
+)
+
+var errTestReader = errors.New("test reader failure")
+
+func (r *failingTFExamplesReader) Read() (*core.TFExample, error) {
+	ex, err := r.testTFExamplesReader.Read()
+	if err == io.EOF {
+		return nil, errTestReader
+	}
+	return ex, err
+}
+
+func (r *tfExamplesSliceReader) Read() (*core.TFExample, error) {
+	if len(r.examples) == 0 {
+		return nil, io.EOF
+	}
+	ex := r.examples[0]
+	r.examples = r.examples[1:]
+	return ex, nil
+}
+
+func manyFeaturesExamples(cnt int) []*core.TFExample {
+	examples := make([]*core.TFExample, 0, cnt)
+	for i := 0; i < cnt; i++ {
+		ex := core.NewTFExample()
+		for f := 0; f < 10; f++ {
+			ex.AddInt64(fmt.Sprintf("feature-%d", f), int64(i*f))
+		}
+		examples = append(examples, ex)
+	}
+	return examples
+}
+
+func TestTfRecordWriteMessagesOrdered(t *testing.T) {
+	const cnt = 1000
+	examples := manyFeaturesExamples(cnt)
+
+	sequential := bytes.NewBuffer(nil)
+	err := core.NewTFRecordWriter(sequential).WriteMessages(&tfExamplesSliceReader{examples: examples})
+	tassert.CheckFatal(t, err)
+
+	for _, workers := range []int{1, 3, 16} {
+		parallel := bytes.NewBuffer(nil)
+		err := core.NewTFRecordWriter(parallel).WriteMessagesOrdered(&tfExamplesSliceReader{examples: examples}, workers)
+		tassert.CheckFatal(t, err)
+		tassert.Errorf(t, bytes.Equal(sequential.Bytes(), parallel.Bytes()), "expected ordered writing with %d workers to be byte-identical to sequential one", workers)
+	}
+
+	err = core.NewTFRecordWriter(ioutil.Discard).WriteMessagesOrdered(&failingTFExamplesReader{testTFExamplesReader{size: 100}}, 4)
+	tassert.Errorf(t, err == errTestReader, "expected reader error, got %v", err)
+}
+
+func TestPipelineOrdered(t *testing.T) {
+	const cnt = 200
+	files := make([]tarFile, 0, 2*cnt)
+	for i := 0; i < cnt; i++ {
+		files = append(files, tarFile{fmt.Sprintf("sample%04d.txt", i), []byte(fmt.Sprintf("text %d", i))})
+		if i%7 != 0 {
+			files = append(files, tarFile{fmt.Sprintf("sample%04d.cls", i), []byte{byte(i)}})
+		}
+	}
+	source, err := prepareTar(files)
+	tassert.CheckFatal(t, err)
+
+	run := func(ordered bool) []byte {
+		sink := bytes.NewBuffer(nil)
+		p := pipeline.NewPipeline().FromTar(bytes.NewReader(source.Bytes()))
+		// samples without cls are dropped
+		p.TransformSamples(transform.SampleF(func(s core.Sample) core.Sample {
+			if _, ok := s["cls"]; !ok {
+				return core.NewSample()
+			}
+			s["txt"] = bytes.ToUpper(s["txt"].([]byte))
+			return s
+		})).FilterEmptySamples()
+		p.SampleToTFExample(core.TypesMap{"txt": core.FeatureType.BYTES}).ToTFRecord(sink)
+		if ordered {
+			p.Ordered(8, 5)
+		}
+		tassert.CheckFatal(t, p.Do())
+		return sink.Bytes()
+	}
+
+	sequential := run(false)
+	examples, err := core.NewTFRecordReader(bytes.NewReader(sequential)).ReadAllExamples()
+	tassert.CheckFatal(t, err)
+	tassert.Fatalf(t, len(examples) == cnt-(cnt+6)/7, "expected %d examples, got %d", cnt-(cnt+6)/7, len(examples))
+	for i := 0; i < 3; i++ {
+		tassert.Errorf(t, bytes.Equal(sequential, run(true)), "expected ordered pipeline to be byte-identical to sequential one")
+	}
+}
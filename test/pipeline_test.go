@@ -3,10 +3,17 @@
 package test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/archive"
 	"github.com/NVIDIA/go-tfdata/tfdata/core"
 	"github.com/NVIDIA/go-tfdata/tfdata/pipeline"
 	"github.com/NVIDIA/go-tfdata/tfdata/transform"
@@ -182,3 +189,174 @@ func TestPipeline(t *testing.T) {
 	tassert.CheckFatal(t, err)
 	tassert.Fatalf(t, len(examples) == examplesCnt, "expected to read %d examples, but got %d", examplesCnt, len(examples))
 }
+
+func TestPipelineCompressedTFRecord(t *testing.T) {
+	const (
+		sourcePath  = "data/small-10.tar"
+		examplesCnt = 10
+	)
+	var (
+		sourceFd *os.File
+		err      error
+		examples []*core.TFExample
+		sink     = bytes.NewBuffer(nil)
+	)
+
+	sourceFd, err = os.Open(sourcePath)
+	tassert.CheckFatal(t, err)
+	defer sourceFd.Close()
+
+	p := pipeline.NewPipeline().FromTar(sourceFd).SampleToTFExample()
+	// declare that TFExamples should be written to sink in GZIP compressed TFRecord format
+	p.ToTFRecordWithOptions(sink, core.TFRecordOptions{Compression: core.GzipCompression})
+	err = p.Do()
+	tassert.CheckFatal(t, err)
+
+	r := core.NewTFRecordReader(sink, core.TFRecordOptions{Compression: core.AutoCompression})
+	examples, err = r.ReadAllExamples(examplesCnt)
+	tassert.CheckFatal(t, err)
+	tassert.Fatalf(t, len(examples) == examplesCnt, "expected to read %d examples, but got %d", examplesCnt, len(examples))
+}
+
+func TestPipelineTarGzFromPipe(t *testing.T) {
+	const examplesCnt = 10
+	for _, stream := range []bool{false, true} {
+		source, err := ioutil.ReadFile("data/small-10.tar.gz")
+		tassert.CheckFatal(t, err)
+		if stream {
+			source = gzipFile(t, "data/small-10.tar") // entries of samples have to be contiguous
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			_, err := pw.Write(source)
+			pw.CloseWithError(err)
+		}()
+
+		sink := bytes.NewBuffer(nil)
+		p := pipeline.NewPipeline()
+		if stream {
+			p.FromTarGzStream(pr)
+		} else {
+			p.FromTarGz(pr)
+		}
+		err = p.SampleToTFExample().ToTFRecord(sink).Do()
+		tassert.CheckFatal(t, err)
+
+		examples, err := core.NewTFRecordReader(sink).ReadAllExamples(examplesCnt + 1)
+		tassert.CheckFatal(t, err)
+		tassert.Errorf(t, len(examples) == examplesCnt, "expected to read %d examples, but got %d", examplesCnt, len(examples))
+	}
+}
+
+func TestPipelineTFRecordToTar(t *testing.T) {
+	const samplesCnt = 10
+	source, err := os.Open("data/small-10.tar")
+	tassert.CheckFatal(t, err)
+	defer source.Close()
+
+	tfRecord := bytes.NewBuffer(nil)
+	err = pipeline.NewPipeline().FromTar(source).SampleToTFExample(core.TypesMap{
+		"cls": core.FeatureType.BYTES,
+		"jpg": core.FeatureType.BYTES,
+	}).ToTFRecord(tfRecord).Do()
+	tassert.CheckFatal(t, err)
+
+	sink := bytes.NewBuffer(nil)
+	err = pipeline.NewPipeline().FromTFRecord(tfRecord).TFExampleToSample().ToTar(sink, archive.TarWriterOptions{Gzip: true}).Do()
+	tassert.CheckFatal(t, err)
+
+	_, err = source.Seek(0, io.SeekStart)
+	tassert.CheckFatal(t, err)
+	expected := make(map[string]core.Sample)
+	tr, err := archive.NewTarReader(source)
+	tassert.CheckFatal(t, err)
+	for sample, err := tr.Read(); err != io.EOF; sample, err = tr.Read() {
+		tassert.CheckFatal(t, err)
+		expected[sample[core.KeyEntry].(string)] = sample
+	}
+
+	sr, err := archive.NewTarGzStreamReader(sink, archive.TarStreamOptions{VerifyContiguous: true})
+	tassert.CheckFatal(t, err)
+	cnt := 0
+	for sample, err := sr.Read(); err != io.EOF; sample, err = sr.Read() {
+		tassert.CheckFatal(t, err)
+		key := sample[core.KeyEntry].(string)
+		tassert.Fatalf(t, expected[key] != nil, "unexpected sample %s", key)
+		for _, ext := range []string{"cls", "jpg"} {
+			tassert.Errorf(t, bytes.Equal(sample[ext].([]byte), expected[key][ext].([]byte)), "sample %s: %s differs", key, ext)
+		}
+		cnt++
+	}
+	tassert.Errorf(t, cnt == samplesCnt, "expected %d samples, got %d", samplesCnt, cnt)
+}
+
+func TestPipelineTFRecordToTarFeatureNames(t *testing.T) {
+	const cnt = 5
+	tfRecord := bytes.NewBuffer(nil)
+	w := core.NewTFRecordWriter(tfRecord)
+	for i := 0; i < cnt; i++ {
+		ex := core.NewTFExample()
+		ex.AddBytes("image/encoded", []byte{byte(i)})
+		ex.AddInt("image/class/label", i)
+		ex.AddFloat("image/object/bbox.xmin", 0.5)
+		_, err := w.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+	}
+
+	tar := bytes.NewBuffer(nil)
+	err := pipeline.NewPipeline().FromTFRecord(tfRecord).ToTar(tar).Do()
+	tassert.CheckFatal(t, err)
+
+	// each of TFExamples has to be read back as a single Sample, with all of features
+	sink := bytes.NewBuffer(nil)
+	err = pipeline.NewPipeline().FromTar(tar).SampleToTFExample(core.TypesMap{
+		"image_encoded":          core.FeatureType.BYTES,
+		"image_class_label":      core.FeatureType.BYTES,
+		"image_object_bbox_xmin": core.FeatureType.BYTES,
+	}).ToTFRecord(sink).Do()
+	tassert.CheckFatal(t, err)
+
+	examples, err := core.NewTFRecordReader(sink).ReadAllExamples()
+	tassert.CheckFatal(t, err)
+	tassert.Fatalf(t, len(examples) == cnt, "expected %d examples, got %d", cnt, len(examples))
+	for i, ex := range examples {
+		image := ex.GetBytesList("image_encoded")
+		tassert.Errorf(t, bytes.Equal(image, []byte{byte(i)}), "example %d: unexpected image %v", i, image)
+		label := ex.GetBytesList("image_class_label")
+		tassert.Errorf(t, string(label) == fmt.Sprint(i), "example %d: unexpected label %q", i, label)
+		tassert.Errorf(t, ex.HasFeature("image_object_bbox_xmin"), "example %d: expected bbox feature", i)
+	}
+
+	// TarWriter rejects entries which can't be read back
+	sample := core.Sample{core.KeyEntry: "0", "image/encoded": []byte{0}}
+	err = archive.NewTarWriter(ioutil.Discard).Write(sample)
+	tassert.Errorf(t, errors.Is(err, archive.ErrInvalidEntryName), "expected ErrInvalidEntryName, got %v", err)
+}
+
+func TestPipelineTFRecordToTarShardsError(t *testing.T) {
+	data, _ := prepareTFRecord(t, 10)
+	files := make(map[string]*memFile)
+	w, err := archive.NewTarShardWriter(archive.TarShardOptions{
+		Pattern:    "shard-%d-of-%d.tar",
+		MaxSamples: 3,
+		Create: func(name string) (io.WriteCloser, error) {
+			files[name] = &memFile{}
+			return files[name], nil
+		},
+		Rename: func(oldName, newName string) error {
+			t.Errorf("unexpected rename of %s to %s", oldName, newName)
+			return nil
+		},
+	})
+	tassert.CheckFatal(t, err)
+
+	// the last record is truncated, so the pipeline fails after writing all of the shards
+	err = pipeline.NewPipeline().FromTFRecord(bytes.NewReader(data[:len(data)-1])).ToTarShards(w).Do()
+	tassert.Fatalf(t, err != nil, "expected error on truncated TFRecord")
+	shards := w.Shards()
+	tassert.Fatalf(t, len(shards) == 3, "expected 3 shards, got %d", len(shards))
+	for _, shard := range shards {
+		tassert.Errorf(t, files[shard.Name] != nil && strings.HasSuffix(shard.Name, "-of-0.tar"),
+			"expected shard %s to keep its temporary name", shard.Name)
+	}
+}
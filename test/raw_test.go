@@ -0,0 +1,107 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+)
+
+// memFile is in-memory shard for TFRecordShardWriter
+type memFile struct {
+	bytes.Buffer
+}
+
+func (*memFile) Close() error { return nil }
+
+func TestTfRecordRawCopy(t *testing.T) {
+	data, _ := prepareTFRecord(t, 10)
+
+	// recompress records without decoding them
+	compressed := bytes.NewBuffer(nil)
+	w := core.NewTFRecordWriter(compressed, core.TFRecordOptions{Compression: core.GzipCompression})
+	tassert.CheckFatal(t, w.WriteRecords(core.NewTFRecordReader(bytes.NewReader(data))))
+	tassert.CheckFatal(t, w.Close())
+
+	uncompressed := bytes.NewBuffer(nil)
+	w = core.NewTFRecordWriter(uncompressed)
+	r := core.NewTFRecordReader(compressed, core.TFRecordOptions{Compression: core.GzipCompression})
+	var records [][]byte
+	for {
+		p, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+		tassert.CheckFatal(t, err)
+		records = append(records, p)
+		_, err = w.WriteRecord(p)
+		tassert.CheckFatal(t, err)
+	}
+	tassert.Errorf(t, bytes.Equal(data, uncompressed.Bytes()), "expected records to be copied byte-for-byte")
+
+	// records returned by ReadRecord are not reused
+	expected := core.NewTFRecordReader(bytes.NewReader(data))
+	for i, p := range records {
+		e, err := expected.ReadRecord()
+		tassert.CheckFatal(t, err)
+		tassert.Errorf(t, bytes.Equal(p, e), "record %d differs", i)
+	}
+}
+
+func TestTfRecordRawChecksum(t *testing.T) {
+	data, size := prepareTFRecord(t, 3)
+	data[size+20] ^= 0xff
+
+	w := core.NewTFRecordWriter(bytes.NewBuffer(nil))
+	err := w.WriteRecords(core.NewTFRecordReader(bytes.NewReader(data)))
+	var payloadErr *core.PayloadChecksumError
+	tassert.Errorf(t, errors.As(err, &payloadErr), "expected PayloadChecksumError, got %v", err)
+}
+
+func TestTfRecordRawReshard(t *testing.T) {
+	data, size := prepareTFRecord(t, 10)
+	files := make(map[string]*memFile)
+	w, err := core.NewTFRecordShardWriter(core.TFRecordShardOptions{
+		Pattern:     "shard-%d-of-%d",
+		MaxExamples: 4,
+		Create: func(name string) (io.WriteCloser, error) {
+			files[name] = &memFile{}
+			return files[name], nil
+		},
+		Rename: func(oldName, newName string) error {
+			files[newName] = files[oldName]
+			delete(files, oldName)
+			return nil
+		},
+	})
+	tassert.CheckFatal(t, err)
+	tassert.CheckFatal(t, w.WriteRecords(core.NewTFRecordReader(bytes.NewReader(data))))
+	tassert.CheckFatal(t, w.Close())
+
+	tassert.Fatalf(t, len(files) == 3, "expected 3 shards, got %d", len(files))
+	var merged []byte
+	for i := 0; i < 3; i++ {
+		merged = append(merged, files[fmt.Sprintf("shard-%d-of-3", i)].Bytes()...)
+	}
+	tassert.Errorf(t, bytes.Equal(merged, data), "expected shards to be byte-for-byte split of TFRecord")
+
+	index, err := indexOf(data)
+	tassert.CheckFatal(t, err)
+	p, err := core.NewTFRecordRandomReader(bytes.NewReader(data), index).ReadRecordAt(5)
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, bytes.Equal(p, data[5*size+12:6*size-4]), "unexpected payload of record 5")
+}
+
+func indexOf(data []byte) (core.TFRecordIndex, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := core.BuildTFRecordIndex(bytes.NewReader(data), buf); err != nil {
+		return nil, err
+	}
+	return core.ReadTFRecordIndex(buf)
+}
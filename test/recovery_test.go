@@ -0,0 +1,128 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+)
+
+func TestTfRecordCorruptionSkip(t *testing.T) {
+	const cnt = 6
+	var (
+		data   = bytes.NewBuffer(nil)
+		w      = core.NewTFRecordWriter(data)
+		record int64
+	)
+	for i := 0; i < cnt; i++ {
+		ex := core.NewTFExample()
+		ex.AddInt64("id", int64(i))
+		ex.AddBytes("bytes", []byte("bytesstring"))
+		n, err := w.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+		record = int64(n)
+	}
+
+	// corrupt length header of record 1, payload of record 3 and truncate record 5
+	corrupted := data.Bytes()
+	corrupted[record] ^= 0xff
+	corrupted[3*record+15] ^= 0xff
+	corrupted = corrupted[:len(corrupted)-5]
+
+	_, err := core.NewTFRecordReader(bytes.NewReader(corrupted)).ReadAllExamples()
+	tassert.Fatalf(t, err != nil, "expected error on corrupted TFRecord in strict mode")
+
+	var corruptions []core.TFRecordCorruption
+	r := core.NewTFRecordReader(bytes.NewReader(corrupted), core.TFRecordOptions{
+		Corruption:   core.CorruptionSkip,
+		OnCorruption: func(c core.TFRecordCorruption) { corruptions = append(corruptions, c) },
+	})
+	examples, err := r.ReadAllExamples()
+	tassert.CheckFatal(t, err)
+
+	tassert.Fatalf(t, len(examples) == 3, "expected 3 examples, got %d", len(examples))
+	for i, id := range []int64{0, 2, 4} {
+		tassert.Errorf(t, examples[i].GetInt64("id") == id, "expected example %d to have id %d, got %d", i, id, examples[i].GetInt64("id"))
+	}
+
+	expected := []core.TFRecordCorruption{
+		{Offset: record, Length: record, Index: 1},
+		{Offset: 3 * record, Length: record, Index: 2},
+		{Offset: 5 * record, Length: record - 5, Index: 4},
+	}
+	tassert.Fatalf(t, len(corruptions) == len(expected), "expected %d corruptions, got %d", len(expected), len(corruptions))
+	for i, c := range corruptions {
+		tassert.Errorf(t, c.Err != nil, "expected corruption %d to have a reason", i)
+		c.Err = nil
+		tassert.Errorf(t, c == expected[i], "expected corruption %d to be %+v, got %+v", i, expected[i], c)
+	}
+}
+
+func TestTfRecordCorruptionSkipGarbage(t *testing.T) {
+	data := bytes.NewBuffer(nil)
+	data.Write(bytes.Repeat([]byte{0x42}, 100))
+	tassert.CheckFatal(t, writeExamples(data, prepareExamples(2)))
+	data.Write(bytes.Repeat([]byte{0x42}, 50000))
+
+	var skipped int64
+	r := core.NewTFRecordReader(data, core.TFRecordOptions{
+		Corruption:   core.CorruptionSkip,
+		OnCorruption: func(c core.TFRecordCorruption) { skipped += c.Length },
+	})
+	examples, err := r.ReadAllExamples()
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, len(examples) == 2, "expected 2 examples, got %d", len(examples))
+	tassert.Errorf(t, skipped == 50100, "expected 50100 bytes to be skipped, got %d", skipped)
+}
+
+func TestTfRecordCorruptionSkipTruncatedAfterResync(t *testing.T) {
+	data := bytes.NewBuffer(nil)
+	tassert.CheckFatal(t, writeExamples(data, prepareExamples(3)))
+	record := int64(data.Len() / 3)
+
+	// corrupt length header of record 1 and truncate record 2 in the middle of its length header,
+	// so looking for the next valid header reaches the end of TFRecord
+	corrupted := data.Bytes()[:2*record+5]
+	corrupted[record] ^= 0xff
+
+	sources := map[string]func() io.Reader{
+		"known size":   func() io.Reader { return bytes.NewReader(corrupted) },
+		"unknown size": func() io.Reader { return bytes.NewBuffer(corrupted) },
+	}
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			var corruptions []core.TFRecordCorruption
+			r := core.NewTFRecordReader(source(), core.TFRecordOptions{
+				Corruption:   core.CorruptionSkip,
+				OnCorruption: func(c core.TFRecordCorruption) { corruptions = append(corruptions, c) },
+			})
+			examples, err := r.ReadAllExamples()
+			tassert.CheckFatal(t, err)
+			tassert.Errorf(t, len(examples) == 1, "expected 1 example, got %d", len(examples))
+
+			// bytes which might be a beginning of a length header are reported as truncated record
+			const tail = 11
+			tassert.Fatalf(t, len(corruptions) == 2, "expected 2 corruptions, got %d", len(corruptions))
+			var headerErr *core.HeaderChecksumError
+			tassert.Errorf(t, errors.As(corruptions[0].Err, &headerErr), "expected HeaderChecksumError, got %v", corruptions[0].Err)
+			var truncatedErr *core.TruncatedRecordError
+			tassert.Fatalf(t, errors.As(corruptions[1].Err, &truncatedErr), "expected TruncatedRecordError, got %v", corruptions[1].Err)
+			tassert.Errorf(t, truncatedErr.Offset == 2*record+5-tail && truncatedErr.Read == tail,
+				"unexpected truncated record %+v", truncatedErr)
+
+			expected := []core.TFRecordCorruption{
+				{Offset: record, Length: record + 5 - tail, Index: 1},
+				{Offset: 2*record + 5 - tail, Length: tail, Index: 1},
+			}
+			for i, c := range corruptions {
+				c.Err = nil
+				tassert.Errorf(t, c == expected[i], "expected corruption %d to be %+v, got %+v", i, expected[i], c)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	"github.com/NVIDIA/go-tfdata/tfdata/schema"
+)
+
+func TestSchemaInfer(t *testing.T) {
+	const cnt = 10
+	buf := bytes.NewBuffer(nil)
+	w := core.NewTFRecordWriter(buf)
+	for i := 0; i < cnt; i++ {
+		ex := core.NewTFExample()
+		ex.AddInt("label", i)
+		ex.AddFloat("bbox", 0.1, 0.2, 0.3, 0.4)
+		ex.AddBytes("image", []byte("image"))
+		if i%2 == 0 {
+			ex.AddInt64List("tags", make([]int64, i%3+1))
+		}
+		if i == 0 {
+			ex.AddBytes("mixed", []byte("bytes"))
+		} else {
+			ex.AddInt("mixed", 1, 2)
+		}
+		_, err := w.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+	}
+
+	s, err := schema.Infer(core.NewTFRecordReader(buf))
+	tassert.CheckFatal(t, err)
+	tassert.Fatalf(t, s.Examples == cnt, "expected %d examples, got %d", cnt, s.Examples)
+	tassert.Fatalf(t, len(s.Features) == 5, "expected 5 features, got %d", len(s.Features))
+
+	expected := []schema.Feature{
+		{Name: "bbox", Kind: schema.KindFloat, MinLength: 4, MaxLength: 4, TypicalLength: 4, Count: cnt, Presence: 1, FixedLength: true},
+		{Name: "image", Kind: schema.KindBytes, MinLength: 1, MaxLength: 1, TypicalLength: 1, Count: cnt, Presence: 1, FixedLength: true},
+		{Name: "label", Kind: schema.KindInt64, MinLength: 1, MaxLength: 1, TypicalLength: 1, Count: cnt, Presence: 1, FixedLength: true},
+		{Name: "mixed", Kind: schema.KindMixed, MinLength: 1, MaxLength: 2, TypicalLength: 2, Count: cnt, Presence: 1},
+		// i = 0, 2, 4, 6, 8 gives lengths 1, 3, 2, 1, 3
+		{Name: "tags", Kind: schema.KindInt64, MinLength: 1, MaxLength: 3, TypicalLength: 1, Count: cnt / 2, Presence: 0.5},
+	}
+	for i := range expected {
+		tassert.Errorf(t, s.Features[i] == expected[i], "expected %+v, got %+v", expected[i], s.Features[i])
+	}
+	tassert.Errorf(t, s.Feature("tags").Count == cnt/2, "expected to find tags feature")
+	tassert.Errorf(t, s.Feature("missing") == nil, "expected nil for missing feature")
+
+	b, err := json.Marshal(s)
+	tassert.CheckFatal(t, err)
+	decoded := &schema.Schema{}
+	tassert.CheckFatal(t, json.Unmarshal(b, decoded))
+	tassert.Errorf(t, decoded.Examples == cnt && len(decoded.Features) == len(expected), "unexpected decoded schema %s", string(b))
+	for i := range expected {
+		tassert.Errorf(t, decoded.Features[i] == expected[i], "expected %+v, got %+v", expected[i], decoded.Features[i])
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/proto"
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	"github.com/NVIDIA/go-tfdata/tfdata/pipeline"
+	"github.com/NVIDIA/go-tfdata/tfdata/transform"
+	"github.com/NVIDIA/go-tfdata/tfdata/transform/selection"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func TestTFSequenceExampleWriterReader(t *testing.T) {
+	const cnt = 10
+	var (
+		buf      = bytes.NewBuffer(nil)
+		w        = core.NewTFRecordWriter(buf)
+		examples = make([]*core.TFSequenceExample, 0, cnt)
+	)
+
+	for i := 0; i < cnt; i++ {
+		ex := core.NewTFSequenceExample()
+		ex.ContextExample().AddInt("length", i)
+		ex.AddInt64FeatureList("ints", [][]int64{{1}, {2, 3}})
+		ex.AddFloatFeatureList("floats", [][]float32{{0.5}, {}, {1.5}})
+		ex.AddBytesFeatureList("frames", [][][]byte{{[]byte("frame0")}, {[]byte("frame1"), []byte("frame1-depth")}})
+		_, err := w.WriteSequenceExample(ex)
+		tassert.CheckFatal(t, err)
+		examples = append(examples, ex)
+	}
+
+	r := core.NewTFRecordReader(bytes.NewReader(buf.Bytes())).SequenceExamples()
+	for i := 0; i < cnt; i++ {
+		ex, err := r.Read()
+		tassert.CheckFatal(t, err)
+		tassert.Errorf(t, protobuf.Equal(ex, examples[i]), "sequence example %d doesn't equal written one", i)
+		tassert.Errorf(t, ex.ContextExample().GetInt64("length") == int64(i), "expected context length %d", i)
+		tassert.Errorf(t, len(ex.GetInt64FeatureList("ints")) == 2, "expected 2 ints steps")
+		tassert.Errorf(t, len(ex.GetFloatFeatureList("floats")[1]) == 0, "expected empty floats step")
+		frames := ex.GetBytesFeatureList("frames")
+		tassert.Errorf(t, len(frames[1]) == 2 && string(frames[1][0]) == "frame1", "unexpected frame %q", frames[1])
+	}
+	_, err := r.Read()
+	tassert.Fatalf(t, err == io.EOF, "expected EOF, got %v", err)
+}
+
+func TestTFSequenceExampleBytesSteps(t *testing.T) {
+	// steps with no values or multiple values are valid in TFSequenceExamples written by TensorFlow
+	ex := core.NewTFSequenceExample()
+	ex.SetFeatureList("tokens", &proto.FeatureList{Feature: []*proto.Feature{
+		{Kind: &proto.Feature_BytesList{BytesList: &proto.BytesList{}}},
+		{Kind: &proto.Feature_BytesList{BytesList: &proto.BytesList{Value: [][]byte{[]byte("a"), []byte("b")}}}},
+	}})
+	buf := bytes.NewBuffer(nil)
+	_, err := core.NewTFRecordWriter(buf).WriteSequenceExample(ex)
+	tassert.CheckFatal(t, err)
+
+	read, err := core.NewTFRecordReader(buf).ReadSequenceExample()
+	tassert.CheckFatal(t, err)
+	steps := read.GetBytesFeatureList("tokens")
+	tassert.Fatalf(t, len(steps) == 2, "expected 2 steps, got %d", len(steps))
+	tassert.Errorf(t, len(steps[0]) == 0 && len(steps[1]) == 2 && string(steps[1][1]) == "b", "unexpected steps %q", steps)
+}
+
+func TestPipelineTFSequenceExample(t *testing.T) {
+	const (
+		videosCnt = 3
+		framesCnt = 12
+	)
+
+	files := make([]tarFile, 0, videosCnt*(framesCnt+1))
+	for v := 0; v < videosCnt; v++ {
+		for f := framesCnt - 1; f >= 0; f-- { // order in TAR shouldn't matter
+			files = append(files, tarFile{fmt.Sprintf("video%d.frame%04d", v, f), []byte(fmt.Sprintf("%d-%d", v, f))})
+		}
+		files = append(files, tarFile{fmt.Sprintf("video%d.cls", v), []byte{byte(v)}})
+	}
+	source, err := prepareTar(files)
+	tassert.CheckFatal(t, err)
+
+	sink := bytes.NewBuffer(nil)
+	p := pipeline.NewPipeline().FromTar(source)
+	p.SampleToTFSequenceExample(transform.SequenceFeatureLists{
+		"frames": {Selection: selection.ByPrefix("frame"), Type: core.FeatureType.BYTES},
+	}, core.TypesMap{"cls": core.FeatureType.BYTES})
+	err = p.ToTFRecord(sink).Do()
+	tassert.CheckFatal(t, err)
+
+	r := core.NewTFRecordReader(sink)
+	cnt := 0
+	for ex, err := r.ReadSequenceExample(); err != io.EOF; ex, err = r.ReadSequenceExample() {
+		tassert.CheckFatal(t, err)
+		frames := ex.GetBytesFeatureList("frames")
+		tassert.Fatalf(t, len(frames) == framesCnt, "expected %d frames, got %d", framesCnt, len(frames))
+		context := ex.ContextExample()
+		tassert.Errorf(t, context.HasFeature("cls") && context.HasFeature(core.KeyEntry), "expected cls and key in context")
+		tassert.Errorf(t, !context.HasFeature("frame0000"), "expected frames not to be in context")
+		v := context.GetBytesList("cls")[0]
+		for i, frame := range frames {
+			expected := fmt.Sprintf("%d-%d", v, i)
+			tassert.Errorf(t, len(frame) == 1 && string(frame[0]) == expected, "expected frame %s, got %q", expected, frame)
+		}
+		cnt++
+	}
+	tassert.Errorf(t, cnt == videosCnt, "expected %d sequence examples, got %d", videosCnt, cnt)
+}
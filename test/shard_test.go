@@ -0,0 +1,138 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	"github.com/NVIDIA/go-tfdata/tfdata/pipeline"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func TestTfRecordShardWriterMaxExamples(t *testing.T) {
+	const cnt = 100
+
+	dir, err := ioutil.TempDir("", "shards")
+	tassert.CheckFatal(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := core.NewTFRecordShardWriter(core.TFRecordShardOptions{
+		Pattern:     filepath.Join(dir, "train-%05d-of-%05d.tfrecord"),
+		MaxExamples: 30,
+	})
+	tassert.CheckFatal(t, err)
+	tfExamples := prepareExamples(cnt)
+	for _, ex := range tfExamples {
+		_, err := w.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+	}
+	tassert.CheckFatal(t, w.Close())
+
+	shards := w.Shards()
+	tassert.Fatalf(t, len(shards) == 4, "expected 4 shards, got %d", len(shards))
+	readCnt := 0
+	for i, shard := range shards {
+		expectedName := filepath.Join(dir, fmt.Sprintf("train-%05d-of-00004.tfrecord", i))
+		tassert.Errorf(t, shard.Name == expectedName, "expected shard name %s, got %s", expectedName, shard.Name)
+
+		fi, err := os.Stat(shard.Name)
+		tassert.CheckFatal(t, err)
+		tassert.Errorf(t, fi.Size() == shard.Bytes, "expected shard to have %d bytes, got %d", shard.Bytes, fi.Size())
+
+		f, err := os.Open(shard.Name)
+		tassert.CheckFatal(t, err)
+		examples, err := core.NewTFRecordReader(f).ReadAllExamples()
+		f.Close()
+		tassert.CheckFatal(t, err)
+		tassert.Errorf(t, len(examples) == shard.Records, "expected shard to have %d records, got %d", shard.Records, len(examples))
+		for _, ex := range examples {
+			tassert.Errorf(t, protobuf.Equal(ex, tfExamples[readCnt]), "example %d doesn't equal written one", readCnt)
+			readCnt++
+		}
+	}
+	tassert.Errorf(t, readCnt == cnt, "expected to read %d examples, got %d", cnt, readCnt)
+
+	// there should be no leftovers with temporary names
+	files, err := ioutil.ReadDir(dir)
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, len(files) == len(shards), "expected %d files, got %d", len(shards), len(files))
+}
+
+func TestTfRecordShardWriterMaxBytes(t *testing.T) {
+	const (
+		cnt      = 100
+		maxBytes = 1024
+	)
+
+	dir, err := ioutil.TempDir("", "shards")
+	tassert.CheckFatal(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := core.NewTFRecordShardWriter(core.TFRecordShardOptions{
+		Pattern:  filepath.Join(dir, "train-%d-of-%d.tfrecord.gz"),
+		MaxBytes: maxBytes,
+		TFRecordOptions: core.TFRecordOptions{
+			Compression: core.GzipCompression,
+		},
+	})
+	tassert.CheckFatal(t, err)
+	tassert.CheckFatal(t, w.WriteMessages(&testTFExamplesReader{size: cnt}))
+	tassert.CheckFatal(t, w.Close())
+
+	shards := w.Shards()
+	tassert.Fatalf(t, len(shards) > 1, "expected multiple shards, got %d", len(shards))
+	records := 0
+	for _, shard := range shards {
+		f, err := os.Open(shard.Name)
+		tassert.CheckFatal(t, err)
+		examples, err := core.NewTFRecordReader(f, core.TFRecordOptions{Compression: core.AutoCompression}).ReadAllExamples()
+		f.Close()
+		tassert.CheckFatal(t, err)
+		tassert.Errorf(t, len(examples) == shard.Records, "expected shard to have %d records, got %d", shard.Records, len(examples))
+		records += shard.Records
+	}
+	tassert.Errorf(t, records == cnt, "expected to write %d records, got %d", cnt, records)
+}
+
+func TestTfRecordShardWriterInvalidOptions(t *testing.T) {
+	for _, pattern := range []string{"", "shard.tfrecord", "shard-%d.tfrecord", "%s-of-%s.tfrecord"} {
+		_, err := core.NewTFRecordShardWriter(core.TFRecordShardOptions{Pattern: pattern})
+		tassert.Errorf(t, err != nil, "expected error for pattern %q", pattern)
+	}
+	_, err := core.NewTFRecordShardWriter(core.TFRecordShardOptions{
+		Pattern:         "%d-of-%d.tfrecord",
+		TFRecordOptions: core.TFRecordOptions{Index: ioutil.Discard},
+	})
+	tassert.Errorf(t, err != nil, "expected error for TFRecord index")
+}
+
+func TestPipelineTFRecordShards(t *testing.T) {
+	const sourcePath = "data/small-10.tar"
+
+	dir, err := ioutil.TempDir("", "shards")
+	tassert.CheckFatal(t, err)
+	defer os.RemoveAll(dir)
+
+	sourceFd, err := os.Open(sourcePath)
+	tassert.CheckFatal(t, err)
+	defer sourceFd.Close()
+
+	w, err := core.NewTFRecordShardWriter(core.TFRecordShardOptions{
+		Pattern:     filepath.Join(dir, "small-%02d-of-%02d.tfrecord"),
+		MaxExamples: 3,
+	})
+	tassert.CheckFatal(t, err)
+	err = pipeline.NewPipeline().FromTar(sourceFd).SampleToTFExample().ToTFRecordShards(w, 4).Do()
+	tassert.CheckFatal(t, err)
+
+	shards := w.Shards()
+	tassert.Fatalf(t, len(shards) == 4, "expected 4 shards, got %d", len(shards))
+	tassert.Errorf(t, shards[3].Name == filepath.Join(dir, "small-03-of-04.tfrecord"), "unexpected last shard name %s", shards[3].Name)
+	tassert.Errorf(t, shards[3].Records == 1, "expected last shard to have 1 record, got %d", shards[3].Records)
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	"github.com/NVIDIA/go-tfdata/tfdata/pipeline"
+	"github.com/NVIDIA/go-tfdata/tfdata/spec"
+)
+
+var testParserConfig = &spec.ExampleParserConfiguration{FeatureMap: map[string]spec.FeatureConfiguration{
+	"label": {FixedLen: &spec.FixedLenFeature{DType: spec.DTypeInt64}},
+	"bbox":  {FixedLen: &spec.FixedLenFeature{DType: spec.DTypeFloat, Shape: []int64{2, 2}}},
+	"weight": {FixedLen: &spec.FixedLenFeature{
+		DType: spec.DTypeFloat, DefaultValue: []float32{1},
+	}},
+	"tags": {VarLen: &spec.VarLenFeature{DType: spec.DTypeString}},
+}}
+
+func validExample() *core.TFExample {
+	ex := core.NewTFExample()
+	ex.AddInt("label", 1)
+	ex.AddFloat("bbox", 0.1, 0.2, 0.3, 0.4)
+	ex.AddBytes("tags", []byte("a"), []byte("b"))
+	ex.AddBytes("extra", []byte("ignored"))
+	return ex
+}
+
+func TestSpecValidate(t *testing.T) {
+	tassert.CheckFatal(t, testParserConfig.Check())
+	tassert.CheckFatal(t, spec.Validate(validExample(), testParserConfig))
+
+	ex := validExample()
+	delete(ex.Features.Feature, "tags") // VarLen features are optional
+	tassert.CheckFatal(t, spec.Validate(ex, testParserConfig))
+
+	ex = validExample()
+	delete(ex.Features.Feature, "label")
+	ex.AddFloat("bbox", 1, 2, 3)
+	ex.AddInt("tags", 1)
+	err := spec.Validate(ex, testParserConfig)
+	invalid := &spec.InvalidExampleError{}
+	tassert.Fatalf(t, errors.As(err, &invalid), "expected InvalidExampleError, got %v", err)
+	expected := []string{"bbox", "label", "tags"}
+	tassert.Fatalf(t, len(invalid.Violations) == len(expected), "expected %d violations, got %v", len(expected), err)
+	for i, v := range invalid.Violations {
+		tassert.Errorf(t, v.Feature == expected[i], "expected violation of %s, got %s: %s", expected[i], v.Feature, v.Reason)
+	}
+
+	badConfig := &spec.ExampleParserConfiguration{FeatureMap: map[string]spec.FeatureConfiguration{
+		"x": {FixedLen: &spec.FixedLenFeature{DType: spec.DTypeInt64, Shape: []int64{2}, DefaultValue: []int64{1}}},
+	}}
+	tassert.Errorf(t, badConfig.Check() != nil, "expected default value with invalid shape to be rejected")
+	_, err = spec.NewValidatingReader(&tfExamplesSliceReader{}, badConfig, spec.ValidationFail)
+	tassert.Errorf(t, err != nil, "expected ValidatingReader with invalid config to be rejected")
+	err = pipeline.NewPipeline().WithTarStage(func() (core.SampleReader, error) { return nil, nil }).
+		ValidateTFExamples(badConfig, spec.ValidationFail).ToTFRecord(ioutil.Discard).Do()
+	tassert.Errorf(t, err != nil, "expected pipeline with invalid config to fail")
+}
+
+func TestPipelineValidateTFExamples(t *testing.T) {
+	const cnt = 10
+	examples := make([]*core.TFExample, 0, cnt)
+	for i := 0; i < cnt; i++ {
+		ex := validExample()
+		if i%3 == 0 {
+			ex.AddInt("label", 1, 2)
+		}
+		examples = append(examples, ex)
+	}
+
+	for _, policy := range []spec.ValidationPolicy{spec.ValidationFail, spec.ValidationSkip, spec.ValidationReport} {
+		var (
+			sink     = bytes.NewBuffer(nil)
+			reported = 0
+		)
+		p := pipeline.NewPipeline().WithTarStage(func() (core.SampleReader, error) { return nil, nil })
+		p.WithSample2TFExampleStage(func(core.SampleReader) core.TFExampleReader {
+			return &tfExamplesSliceReader{examples: examples}
+		})
+		p.ValidateTFExamples(testParserConfig, policy, func(ex *core.TFExample, err *spec.InvalidExampleError) {
+			reported++
+			tassert.Errorf(t, len(err.Violations) == 1 && err.Violations[0].Feature == "label", "unexpected violations %v", err)
+		})
+		err := p.ToTFRecord(sink).Do()
+
+		if policy == spec.ValidationFail {
+			tassert.Errorf(t, reported == 1, "expected validation to stop on the first invalid TFExample")
+			invalid := &spec.InvalidExampleError{}
+			tassert.Errorf(t, errors.As(err, &invalid), "expected InvalidExampleError, got %v", err)
+			continue
+		}
+		tassert.CheckFatal(t, err)
+		tassert.Errorf(t, reported == 4, "expected 4 invalid TFExamples to be reported, got %d", reported)
+
+		written := 0
+		r := core.NewTFRecordReader(sink)
+		for _, err := r.Read(); err != io.EOF; _, err = r.Read() {
+			tassert.CheckFatal(t, err)
+			written++
+		}
+		expected := cnt
+		if policy == spec.ValidationSkip {
+			expected = cnt - 4
+		}
+		tassert.Errorf(t, written == expected, "expected %d TFExamples written, got %d", expected, written)
+	}
+}
+
+func TestSpecParseExamples(t *testing.T) {
+	examples := []*core.TFExample{validExample(), validExample(), validExample()}
+	examples[0].AddFloat("weight", 0.5)
+	examples[1].AddBytes("tags", []byte("c"))
+	delete(examples[2].Features.Feature, "tags")
+
+	parsed, err := spec.ParseExamples(examples, testParserConfig)
+	tassert.CheckFatal(t, err)
+
+	bbox := parsed.Dense["bbox"]
+	tassert.Errorf(t, equalInts(bbox.Shape, []int64{3, 2, 2}), "unexpected bbox shape %v", bbox.Shape)
+	tassert.Errorf(t, len(bbox.Floats) == 12 && bbox.Floats[5] == 0.2, "unexpected bbox values %v", bbox.Floats)
+	weight := parsed.Dense["weight"]
+	tassert.Errorf(t, equalInts(weight.Shape, []int64{3}), "unexpected weight shape %v", weight.Shape)
+	tassert.Errorf(t, len(weight.Floats) == 3 && weight.Floats[0] == 0.5 && weight.Floats[1] == 1 && weight.Floats[2] == 1,
+		"expected default weight for examples without it, got %v", weight.Floats)
+
+	tags := parsed.Sparse["tags"]
+	tassert.Errorf(t, equalInts(tags.DenseShape, []int64{3, 2}), "unexpected tags dense shape %v", tags.DenseShape)
+	expectedIndices := [][]int64{{0, 0}, {0, 1}, {1, 0}}
+	tassert.Fatalf(t, len(tags.Indices) == len(expectedIndices), "unexpected tags indices %v", tags.Indices)
+	for i := range expectedIndices {
+		tassert.Errorf(t, equalInts(tags.Indices[i], expectedIndices[i]), "expected index %v, got %v", expectedIndices[i], tags.Indices[i])
+	}
+	tassert.Errorf(t, string(tags.Values.Bytes[2]) == "c", "unexpected tags values")
+
+	single, err := spec.ParseExample(examples[0], testParserConfig)
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, equalInts(single.Dense["bbox"].Shape, []int64{2, 2}), "unexpected single bbox shape")
+	tassert.Errorf(t, equalInts(single.Sparse["tags"].DenseShape, []int64{2}), "unexpected single tags dense shape")
+
+	delete(examples[1].Features.Feature, "label")
+	_, err = spec.ParseExamples(examples, testParserConfig)
+	invalid := &spec.InvalidExampleError{}
+	tassert.Errorf(t, errors.As(err, &invalid), "expected InvalidExampleError, got %v", err)
+}
+
+func equalInts(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -3,10 +3,17 @@
 package test
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/NVIDIA/go-tfdata/test/tassert"
@@ -119,3 +126,329 @@ func TestInvalidSeekTarError(t *testing.T) {
 	_, err = r.Read()
 	tassert.Fatalf(t, err != nil && err != io.EOF, "expected TAR read failure, got %v", err)
 }
+
+// nonSeekable hides io.Seeker of the underlying reader
+type nonSeekable struct {
+	r io.Reader
+}
+
+func (n *nonSeekable) Read(p []byte) (int, error) { return n.r.Read(p) }
+
+func TestTarStreamReader(t *testing.T) {
+	f, err := os.Open("data/small-mnist-21.tar")
+	tassert.CheckFatal(t, err)
+	defer f.Close()
+	tr, err := archive.NewTarReader(f)
+	tassert.CheckFatal(t, err)
+	expected := make(map[string]core.Sample)
+	for sample, err := tr.Read(); err != io.EOF; sample, err = tr.Read() {
+		tassert.CheckFatal(t, err)
+		expected[sample[core.KeyEntry].(string)] = sample
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	tassert.CheckFatal(t, err)
+	sr := archive.NewTarStreamReader(&nonSeekable{f}, archive.TarStreamOptions{VerifyContiguous: true})
+	i := 0
+	for sample, err := sr.Read(); err != io.EOF; sample, err = sr.Read() {
+		tassert.CheckFatal(t, err)
+		e, ok := expected[sample[core.KeyEntry].(string)]
+		tassert.Fatalf(t, ok, "unexpected sample %v", sample[core.KeyEntry])
+		tassert.Errorf(t, len(sample) == len(e), "expected %d entries, got %d", len(e), len(sample))
+		for k, v := range e {
+			if k != core.KeyEntry {
+				tassert.Errorf(t, bytes.Equal(sample[k].([]byte), v.([]byte)), "entry %s of %s differs", k, sample[core.KeyEntry])
+			}
+		}
+		i++
+	}
+	tassert.Errorf(t, i == len(expected), "expected %d samples, got %d", len(expected), i)
+}
+
+func TestTarStreamReaderEmitsEarly(t *testing.T) {
+	pr, pw := io.Pipe()
+	tw := tar.NewWriter(pw)
+	writeFile := func(name string) {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 1, Typeflag: tar.TypeReg})
+		if err == nil {
+			_, err = tw.Write([]byte{1})
+		}
+		if err == nil {
+			err = tw.Flush()
+		}
+		if err != nil {
+			pw.CloseWithError(err)
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		writeFile("a.cls")
+		writeFile("a.jpg")
+		writeFile("b.cls")
+		<-done // the rest of TAR is written only after the first sample is read
+		writeFile("b.jpg")
+		pw.CloseWithError(tw.Close())
+	}()
+
+	sr := archive.NewTarStreamReader(pr)
+	sample, err := sr.Read()
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, sample[core.KeyEntry] == "a" && len(sample) == 3, "unexpected first sample %v", sample)
+	close(done)
+	sample, err = sr.Read()
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, sample[core.KeyEntry] == "b" && len(sample) == 3, "unexpected second sample %v", sample)
+	_, err = sr.Read()
+	tassert.Errorf(t, err == io.EOF, "expected EOF, got %v", err)
+}
+
+func TestTarStreamReaderNotContiguous(t *testing.T) {
+	files := []tarFile{{"a.cls", []byte{1}}, {"b.cls", []byte{2}}, {"a.jpg", []byte{3}}}
+	source, err := prepareTar(files)
+	tassert.CheckFatal(t, err)
+	sr := archive.NewTarStreamReader(bytes.NewReader(source.Bytes()))
+	keys := []string{}
+	for sample, err := sr.Read(); err != io.EOF; sample, err = sr.Read() {
+		tassert.CheckFatal(t, err)
+		keys = append(keys, sample[core.KeyEntry].(string))
+	}
+	tassert.Errorf(t, len(keys) == 3 && keys[2] == "a", "expected non-contiguous entries as separate samples, got %v", keys)
+
+	sr = archive.NewTarStreamReader(source, archive.TarStreamOptions{VerifyContiguous: true})
+	_, err = sr.Read()
+	tassert.CheckFatal(t, err)
+	_, err = sr.Read()
+	entryErr := &archive.TarEntryError{}
+	tassert.Fatalf(t, errors.As(err, &entryErr) && entryErr.Key == "a" && errors.Is(err, archive.ErrNotContiguous),
+		"expected ErrNotContiguous, got %v", err)
+	_, err = sr.Read()
+	tassert.Errorf(t, errors.Is(err, archive.ErrNotContiguous), "expected error to be sticky, got %v", err)
+}
+
+// gzipFile returns gzip compressed content of the file path
+func gzipFile(t *testing.T, path string) []byte {
+	data, err := ioutil.ReadFile(path)
+	tassert.CheckFatal(t, err)
+	buf := bytes.NewBuffer(nil)
+	gzw := gzip.NewWriter(buf)
+	_, err = gzw.Write(data)
+	tassert.CheckFatal(t, err)
+	tassert.CheckFatal(t, gzw.Close())
+	return buf.Bytes()
+}
+
+func TestTarGzNonSeekableReaders(t *testing.T) {
+	greedySource, err := ioutil.ReadFile("data/small-10.tar.gz")
+	tassert.CheckFatal(t, err)
+	// entries of samples in small-10.tar.gz are not contiguous, unlike in small-10.tar
+	streamSource := gzipFile(t, "data/small-10.tar")
+
+	readers := map[string]func() (core.SampleReader, error){
+		"greedy": func() (core.SampleReader, error) {
+			return archive.NewTarGzReader(&nonSeekable{bytes.NewReader(greedySource)})
+		},
+		"stream": func() (core.SampleReader, error) {
+			return archive.NewTarGzStreamReader(&nonSeekable{bytes.NewReader(streamSource)})
+		},
+	}
+	for name, newReader := range readers {
+		tr, err := newReader()
+		tassert.CheckFatal(t, err)
+
+		i := 0
+		for sample, err := tr.Read(); err != io.EOF; sample, err = tr.Read() {
+			tassert.CheckFatal(t, err)
+			tassert.Errorf(t, len(sample) == 3 && sample["cls"] != nil && sample["jpg"] != nil,
+				"%s: sample expected to have cls, jpg and key, got %d entries", name, len(sample))
+			i++
+		}
+		tassert.Errorf(t, i == 10, "%s: expected tar to have 10 samples, got %d instead", name, i)
+	}
+}
+
+func TestArchiveReaderDetectsCompression(t *testing.T) {
+	plain, err := ioutil.ReadFile("data/small-10.tar")
+	tassert.CheckFatal(t, err)
+	bz2, err := ioutil.ReadFile("data/small-10.tar.bz2")
+	tassert.CheckFatal(t, err)
+	zbuf := bytes.NewBuffer(nil)
+	zw := zlib.NewWriter(zbuf)
+	_, err = zw.Write(plain)
+	tassert.CheckFatal(t, err)
+	tassert.CheckFatal(t, zw.Close())
+
+	sources := map[string][]byte{
+		"":                       plain,
+		archive.CompressionGzip:  gzipFile(t, "data/small-10.tar"),
+		archive.CompressionBzip2: bz2,
+		archive.CompressionZlib:  zbuf.Bytes(),
+	}
+	for compression, source := range sources {
+		detected := archive.DetectCompression(source[:512])
+		tassert.Errorf(t, detected == compression, "expected %q compression, detected %q", compression, detected)
+
+		readers := map[string]func() (core.SampleReader, error){
+			"seekable":     func() (core.SampleReader, error) { return archive.NewReader(bytes.NewReader(source)) },
+			"non-seekable": func() (core.SampleReader, error) { return archive.NewReader(&nonSeekable{bytes.NewReader(source)}) },
+			"stream": func() (core.SampleReader, error) {
+				return archive.NewStreamReader(&nonSeekable{bytes.NewReader(source)})
+			},
+		}
+		for name, newReader := range readers {
+			tr, err := newReader()
+			tassert.CheckFatal(t, err)
+			i := 0
+			for sample, err := tr.Read(); err != io.EOF; sample, err = tr.Read() {
+				tassert.CheckFatal(t, err)
+				tassert.Errorf(t, len(sample) == 3 && sample["cls"] != nil && sample["jpg"] != nil,
+					"%q %s: sample expected to have cls, jpg and key, got %d entries", compression, name, len(sample))
+				i++
+			}
+			tassert.Errorf(t, i == 10, "%q %s: expected tar to have 10 samples, got %d instead", compression, name, i)
+		}
+	}
+}
+
+func TestArchiveReaderRegisterDecompressor(t *testing.T) {
+	magic := []byte("TESTCOMP")
+	archive.RegisterDecompressor("test", magic, func(r io.Reader) (io.Reader, error) {
+		header := make([]byte, len(magic))
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+
+	files := []tarFile{{"a.cls", []byte{1}}, {"b.cls", []byte{2}}}
+	source, err := prepareTar(files)
+	tassert.CheckFatal(t, err)
+	data, err := ioutil.ReadAll(source)
+	tassert.CheckFatal(t, err)
+	data = append(append([]byte(nil), magic...), data...)
+	tassert.Errorf(t, archive.DetectCompression(data) == "test", "expected registered compression to be detected")
+
+	tr, err := archive.NewReader(&nonSeekable{bytes.NewReader(data)})
+	tassert.CheckFatal(t, err)
+	i := 0
+	for _, err := tr.Read(); err != io.EOF; _, err = tr.Read() {
+		tassert.CheckFatal(t, err)
+		i++
+	}
+	tassert.Errorf(t, i == len(files), "expected %d samples, got %d", len(files), i)
+}
+
+func TestTarWriter(t *testing.T) {
+	f, err := os.Open("data/small-10.tar")
+	tassert.CheckFatal(t, err)
+	defer f.Close()
+
+	for _, gz := range []bool{false, true} {
+		_, err = f.Seek(0, io.SeekStart)
+		tassert.CheckFatal(t, err)
+		tr, err := archive.NewTarReader(f)
+		tassert.CheckFatal(t, err)
+
+		buf := bytes.NewBuffer(nil)
+		w := archive.NewTarWriter(buf, archive.TarWriterOptions{Gzip: gz})
+		written := make(map[string]core.Sample)
+		for sample, err := tr.Read(); err != io.EOF; sample, err = tr.Read() {
+			tassert.CheckFatal(t, err)
+			tassert.CheckFatal(t, w.Write(sample))
+			written[sample[core.KeyEntry].(string)] = sample
+		}
+		tassert.CheckFatal(t, w.Write(core.Sample{core.KeyEntry: "meta", "json": []int{1, 2}, "txt": "text"}))
+		w.Close()
+		tassert.CheckFatal(t, w.Err())
+
+		// written TAR has contiguous samples, so it can be read by TarStreamReader
+		var sr *archive.TarStreamReader
+		if gz {
+			sr, err = archive.NewTarGzStreamReader(buf, archive.TarStreamOptions{VerifyContiguous: true})
+			tassert.CheckFatal(t, err)
+		} else {
+			sr = archive.NewTarStreamReader(buf, archive.TarStreamOptions{VerifyContiguous: true})
+		}
+		i := 0
+		for sample, err := sr.Read(); err != io.EOF; sample, err = sr.Read() {
+			tassert.CheckFatal(t, err)
+			key := sample[core.KeyEntry].(string)
+			if key == "meta" {
+				tassert.Errorf(t, string(sample["json"].([]byte)) == `[1,2]` && string(sample["txt"].([]byte)) == "text",
+					"unexpected meta sample %v", sample)
+				continue
+			}
+			expected, ok := written[key]
+			tassert.Fatalf(t, ok, "unexpected sample %s", key)
+			tassert.Errorf(t, len(sample) == len(expected) && bytes.Equal(sample["jpg"].([]byte), expected["jpg"].([]byte)),
+				"sample %s doesn't equal written one", key)
+			i++
+		}
+		tassert.Errorf(t, i == len(written), "expected %d samples, got %d", len(written), i)
+	}
+
+	w := archive.NewTarWriter(ioutil.Discard)
+	err = w.Write(core.Sample{"cls": []byte{1}})
+	tassert.Errorf(t, err == archive.ErrMissingKey, "expected ErrMissingKey, got %v", err)
+}
+
+func TestTarShardWriter(t *testing.T) {
+	const cnt = 25
+
+	dir, err := ioutil.TempDir("", "tar-shards")
+	tassert.CheckFatal(t, err)
+	defer os.RemoveAll(dir)
+
+	// each of samples takes 2560 bytes in TAR: 512 bytes headers and contents padded to 512 bytes
+	samples := make([]core.Sample, 0, cnt)
+	for i := 0; i < cnt; i++ {
+		samples = append(samples, core.Sample{
+			core.KeyEntry: fmt.Sprintf("sample-%03d", i),
+			"cls":         []byte(strconv.Itoa(i)),
+			"bin":         make([]byte, 1000),
+		})
+	}
+
+	for _, opts := range []archive.TarShardOptions{
+		{Pattern: filepath.Join(dir, "count-%03d-of-%03d.tar"), MaxSamples: 10},
+		{Pattern: filepath.Join(dir, "size-%03d-of-%03d.tar.gz"), MaxBytes: 10 * 2560, TarWriterOptions: archive.TarWriterOptions{Gzip: true}},
+	} {
+		w, err := archive.NewTarShardWriter(opts)
+		tassert.CheckFatal(t, err)
+		for _, s := range samples {
+			tassert.CheckFatal(t, w.Write(s))
+		}
+		w.Close()
+		tassert.CheckFatal(t, w.Err())
+
+		shards := w.Shards()
+		tassert.Fatalf(t, len(shards) == 3, "expected 3 shards, got %d", len(shards))
+		readCnt := 0
+		for i, shard := range shards {
+			expectedName := fmt.Sprintf(opts.Pattern, i, 3)
+			tassert.Errorf(t, shard.Name == expectedName, "expected shard name %s, got %s", expectedName, shard.Name)
+			fi, err := os.Stat(shard.Name)
+			tassert.CheckFatal(t, err)
+			tassert.Errorf(t, fi.Size() == shard.Bytes, "expected shard to have %d bytes, got %d", shard.Bytes, fi.Size())
+
+			f, err := os.Open(shard.Name)
+			tassert.CheckFatal(t, err)
+			tr, err := archive.NewReader(f)
+			tassert.CheckFatal(t, err)
+			n := 0
+			for sample, err := tr.Read(); err != io.EOF; sample, err = tr.Read() {
+				tassert.CheckFatal(t, err)
+				tassert.Errorf(t, len(sample["bin"].([]byte)) == 1000, "unexpected sample %s", sample[core.KeyEntry])
+				n++
+			}
+			f.Close()
+			tassert.Errorf(t, n == shard.Samples, "expected shard to have %d samples, got %d", shard.Samples, n)
+			readCnt += n
+		}
+		tassert.Errorf(t, readCnt == cnt, "expected to read %d samples, got %d", cnt, readCnt)
+	}
+
+	for _, pattern := range []string{"", "shard.tar", "shard-%d.tar", "%s-of-%s.tar", "%d-%d-of-%d.tar", "%[1]d-of-%[1]d.tar"} {
+		_, err := archive.NewTarShardWriter(archive.TarShardOptions{Pattern: pattern})
+		tassert.Errorf(t, err != nil, "expected error for pattern %q", pattern)
+	}
+}
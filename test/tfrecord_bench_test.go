@@ -0,0 +1,289 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-tfdata/test/tassert"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// legacyWriteRecord and legacyReadRecord are copies of TFRecordWriter.Write and TFRecordReader.ReadNext
+// from before buffers were reused. They are used as a reference in benchmarks.
+
+func legacyWriteRecord(w io.Writer, p []byte) (n int, err error) {
+	var (
+		total         = 0
+		lengthHeader  = make([]byte, 12) // uint64(length) + uint32 cksm
+		dataCksmBytes = make([]byte, 4)  // uint32 cksm
+	)
+
+	binary.LittleEndian.PutUint64(lengthHeader[:8], uint64(len(p)))
+	binary.LittleEndian.PutUint32(lengthHeader[8:12], maskedCRC(lengthHeader[:8]))
+	binary.LittleEndian.PutUint32(dataCksmBytes, maskedCRC(p))
+
+	read, err := w.Write(lengthHeader)
+	total += read
+	if err == nil {
+		read, err = w.Write(p)
+		total += read
+	}
+	if err == nil {
+		read, err = w.Write(dataCksmBytes)
+		total += read
+	}
+	return total, err
+}
+
+func legacyReadRecord(r io.Reader) ([]byte, error) {
+	payloadLengthHeader := make([]byte, 12)
+	if _, err := io.ReadFull(r, payloadLengthHeader); err != nil {
+		return nil, err
+	}
+	if maskedCRC(payloadLengthHeader[:8]) != binary.LittleEndian.Uint32(payloadLengthHeader[8:12]) {
+		return nil, fmt.Errorf("invalid header checksum")
+	}
+
+	payloadLength := binary.LittleEndian.Uint64(payloadLengthHeader[0:8])
+	payload := make([]byte, payloadLength)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	payloadChecksumBytes := make([]byte, 4)
+	if _, err := io.ReadFull(r, payloadChecksumBytes); err != nil {
+		return nil, err
+	}
+	if maskedCRC(payload) != binary.LittleEndian.Uint32(payloadChecksumBytes[0:4]) {
+		return nil, fmt.Errorf("invalid payload checksum")
+	}
+	return payload, nil
+}
+
+// countingDiscard counts number of writes
+type countingDiscard struct {
+	writes int
+}
+
+func (d *countingDiscard) Write(p []byte) (int, error) {
+	d.writes++
+	return len(p), nil
+}
+
+func benchPayload(b *testing.B) []byte {
+	p, err := protobuf.Marshal(prepareExamples(1)[0])
+	if err != nil {
+		b.Fatal(err)
+	}
+	return p
+}
+
+func benchTFRecord(b *testing.B, cnt int) []byte {
+	buf := bytes.NewBuffer(nil)
+	w := core.NewTFRecordWriter(buf)
+	payload := benchPayload(b)
+	for i := 0; i < cnt; i++ {
+		if _, err := w.Write(payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func reportRecords(b *testing.B, start time.Time, records int) {
+	b.ReportMetric(float64(records)/time.Since(start).Seconds(), "records/s")
+}
+
+func BenchmarkTFRecordWrite(b *testing.B) {
+	payload := benchPayload(b)
+	b.Run("legacy", func(b *testing.B) {
+		w := &countingDiscard{}
+		b.ReportAllocs()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			if _, err := legacyWriteRecord(w, payload); err != nil {
+				b.Fatal(err)
+			}
+		}
+		reportRecords(b, start, b.N)
+	})
+	b.Run("current", func(b *testing.B) {
+		w := core.NewTFRecordWriter(&countingDiscard{})
+		b.ReportAllocs()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			if _, err := w.Write(payload); err != nil {
+				b.Fatal(err)
+			}
+		}
+		reportRecords(b, start, b.N)
+	})
+	b.Run("buffered", func(b *testing.B) {
+		w := core.NewTFRecordWriter(&countingDiscard{}, core.TFRecordOptions{BufferSize: 64 << 10})
+		b.ReportAllocs()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			if _, err := w.Write(payload); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			b.Fatal(err)
+		}
+		reportRecords(b, start, b.N)
+	})
+}
+
+func BenchmarkTFRecordWriteExample(b *testing.B) {
+	ex := prepareExamples(1)[0]
+	b.Run("legacy", func(b *testing.B) {
+		w := ioutil.Discard
+		b.ReportAllocs()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			p, err := protobuf.Marshal(ex)
+			if err == nil {
+				_, err = legacyWriteRecord(w, p)
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		reportRecords(b, start, b.N)
+	})
+	b.Run("current", func(b *testing.B) {
+		w := core.NewTFRecordWriter(ioutil.Discard)
+		b.ReportAllocs()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			if _, err := w.WriteExample(ex); err != nil {
+				b.Fatal(err)
+			}
+		}
+		reportRecords(b, start, b.N)
+	})
+}
+
+func BenchmarkTFRecordRead(b *testing.B) {
+	const cnt = 10000
+	data := benchTFRecord(b, cnt)
+
+	run := func(b *testing.B, read func(r io.Reader) func() error) {
+		b.ReportAllocs()
+		start, records := time.Now(), 0
+		for i := 0; i < b.N; i++ {
+			next := read(bytes.NewReader(data))
+			for err := next(); err != io.EOF; err = next() {
+				if err != nil {
+					b.Fatal(err)
+				}
+				records++
+			}
+		}
+		reportRecords(b, start, records)
+	}
+
+	b.Run("legacy", func(b *testing.B) {
+		run(b, func(r io.Reader) func() error {
+			return func() error {
+				p, err := legacyReadRecord(r)
+				if err == nil {
+					err = protobuf.Unmarshal(p, &core.TFExample{})
+				}
+				return err
+			}
+		})
+	})
+	b.Run("current", func(b *testing.B) {
+		run(b, func(r io.Reader) func() error {
+			tfReader := core.NewTFRecordReader(r)
+			return func() error {
+				_, err := tfReader.Read()
+				return err
+			}
+		})
+	})
+	b.Run("legacy-raw", func(b *testing.B) {
+		run(b, func(r io.Reader) func() error {
+			return func() error {
+				_, err := legacyReadRecord(r)
+				return err
+			}
+		})
+	})
+	b.Run("borrowed", func(b *testing.B) {
+		run(b, func(r io.Reader) func() error {
+			tfReader := core.NewTFRecordReader(r)
+			return func() error {
+				_, err := tfReader.ReadBorrowed()
+				return err
+			}
+		})
+	})
+}
+
+func TestTfRecordWriterSingleWrite(t *testing.T) {
+	var (
+		w        = &countingDiscard{}
+		tfWriter = core.NewTFRecordWriter(w)
+	)
+	for _, ex := range prepareExamples(10) {
+		_, err := tfWriter.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+	}
+	tassert.Errorf(t, w.writes == 10, "expected single write per record, got %d writes", w.writes)
+
+	w = &countingDiscard{}
+	tfWriter = core.NewTFRecordWriter(w, core.TFRecordOptions{BufferSize: 1 << 20})
+	for _, ex := range prepareExamples(10) {
+		_, err := tfWriter.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+	}
+	tassert.Errorf(t, w.writes == 0, "expected buffered writer to not write before Close, got %d writes", w.writes)
+	tassert.CheckFatal(t, tfWriter.Close())
+	tassert.Errorf(t, w.writes == 1, "expected buffered writer to write once on Close, got %d writes", w.writes)
+}
+
+func TestTfRecordBufferedRoundTrip(t *testing.T) {
+	examples := make([]*core.TFExample, 0, 100)
+	for i := 0; i < 100; i++ {
+		ex := core.NewTFExample()
+		ex.AddBytes("bytes", bytes.Repeat([]byte{byte(i)}, i*10))
+		examples = append(examples, ex)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := core.NewTFRecordWriter(buf, core.TFRecordOptions{BufferSize: 512})
+	for _, ex := range examples {
+		_, err := w.WriteExample(ex)
+		tassert.CheckFatal(t, err)
+	}
+	tassert.CheckFatal(t, w.Close())
+
+	// examples read earlier can't be affected by reused buffers
+	readBack, err := core.NewTFRecordReader(bytes.NewBuffer(buf.Bytes()), core.TFRecordOptions{BufferSize: 512}).ReadAllExamples()
+	tassert.CheckFatal(t, err)
+	tassert.Fatalf(t, len(readBack) == len(examples), "expected %d examples, got %d", len(examples), len(readBack))
+	for i := range examples {
+		tassert.Errorf(t, protobuf.Equal(examples[i], readBack[i]), "example %d differs", i)
+	}
+
+	r := core.NewTFRecordReader(bytes.NewReader(buf.Bytes()))
+	for i := range examples {
+		p, err := r.ReadBorrowed()
+		tassert.CheckFatal(t, err)
+		expected, err := protobuf.Marshal(examples[i])
+		tassert.CheckFatal(t, err)
+		tassert.Errorf(t, bytes.Equal(p, expected), "borrowed payload %d differs", i)
+	}
+	_, err = r.ReadBorrowed()
+	tassert.Errorf(t, err == io.EOF, "expected io.EOF, got %v", err)
+}
@@ -3,6 +3,7 @@
 package test
 
 import (
+	"bytes"
 	"io"
 	"os"
 	"sync"
@@ -236,3 +237,64 @@ func TestTFRecordWriterReaderFromTFExampleReaderAsync(t *testing.T) {
 	tassert.Fatalf(t, err == io.EOF, "expected EOF, got %v", err)
 	tassert.Fatalf(t, cnt == size, "expected to read %d examples, got %d", size, cnt)
 }
+
+// Write compressed TFRecord and read it back with explicit and detected compression
+func TestTfRecordCompression(t *testing.T) {
+	const cnt = 100
+	for _, compression := range []core.CompressionType{core.NoCompression, core.GzipCompression, core.ZlibCompression} {
+		var (
+			buf        = bytes.NewBuffer(nil)
+			tfExamples = prepareExamples(cnt)
+			w          = core.NewTFRecordWriter(buf, core.TFRecordOptions{Compression: compression})
+		)
+		for _, example := range tfExamples {
+			_, err := w.WriteExample(example)
+			tassert.CheckFatal(t, err)
+		}
+		tassert.CheckFatal(t, w.Close())
+
+		switch compression {
+		case core.GzipCompression:
+			tassert.Errorf(t, buf.Bytes()[0] == 0x1f && buf.Bytes()[1] == 0x8b, "expected gzip magic bytes")
+		case core.ZlibCompression:
+			tassert.Errorf(t, buf.Bytes()[0] == 0x78, "expected zlib header")
+		}
+
+		for _, readCompression := range []core.CompressionType{compression, core.AutoCompression} {
+			r := core.NewTFRecordReader(bytes.NewReader(buf.Bytes()), core.TFRecordOptions{Compression: readCompression})
+			readTfExamples, err := r.ReadAllExamples(cnt)
+			tassert.CheckFatal(t, err)
+			tassert.Fatalf(t, len(readTfExamples) == cnt, "%s: expected to read %d examples, but got %d", compression, cnt, len(readTfExamples))
+			for i := range tfExamples {
+				tassert.Errorf(t, protobuf.Equal(tfExamples[i], readTfExamples[i]), "example %s doesn't equal example %s", tfExamples[i].String(), readTfExamples[i].String())
+			}
+		}
+	}
+}
+
+func TestTfRecordInvalidCompression(t *testing.T) {
+	r := core.NewTFRecordReader(bytes.NewReader([]byte("not a gzip stream")), core.TFRecordOptions{Compression: core.GzipCompression})
+	_, err := r.Read()
+	tassert.Fatalf(t, err != nil && err != io.EOF, "expected gzip header error, got %v", err)
+}
+
+func TestTfRecordAutoCompressionNotZlib(t *testing.T) {
+	// length 7304 of the first record is encoded as 0x88 0x1c, which has zlib compression method and
+	// header checksum, but window size too large for zlib
+	buf := bytes.NewBuffer(nil)
+	w := core.NewTFRecordWriter(buf)
+	_, err := w.Write(make([]byte, 7304))
+	tassert.CheckFatal(t, err)
+	tassert.CheckFatal(t, writeExamples(buf, prepareExamples(3)))
+	data := buf.Bytes()
+	tassert.Fatalf(t, data[0] == 0x88 && data[1] == 0x1c, "unexpected length header %x", data[:2])
+	data[8] ^= 0xff // corrupted header checksum makes auto-detection look at magic bytes
+
+	r := core.NewTFRecordReader(bytes.NewReader(data), core.TFRecordOptions{
+		Compression: core.AutoCompression,
+		Corruption:  core.CorruptionSkip,
+	})
+	examples, err := r.ReadAllExamples()
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, len(examples) == 3, "expected 3 examples, got %d", len(examples))
+}
@@ -76,3 +76,60 @@ func TestSelection(t *testing.T) {
 	tassert.Errorf(t, err == io.EOF, "expected io.EOF, got %v", err)
 	tassert.Errorf(t, cnt == size, "expected to read %d samples, got %d", size, cnt)
 }
+
+func TestTFExamplesToSample(t *testing.T) {
+	ex := core.NewTFExample()
+	ex.AddBytes(core.KeyEntry, []byte(`"img-001"`)) // string keys are marshaled to JSON by SamplesToTFExample
+	ex.AddBytes("jpg", []byte{0xff, 0xd8})
+	ex.AddInt("cls", 3)
+	ex.AddInt64List("ids", []int64{-1, 300})
+	ex.AddFloatList("bbox", []float32{0.5, 1.25})
+	ex.AddFloat("score", 0.5)
+	noKey := core.NewTFExample()
+	noKey.AddInt("cls", 1)
+
+	ch := core.NewTFExampleChannel(2)
+	tassert.CheckFatal(t, ch.Write(ex))
+	tassert.CheckFatal(t, ch.Write(noKey))
+	ch.Close()
+
+	r := transform.TFExamplesToSample(ch, transform.SampleEncodings{
+		"ids":   transform.EncodingBinary,
+		"bbox":  transform.EncodingJSON,
+		"score": transform.EncodingBinary,
+	})
+	sample, err := r.Read()
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, sample[core.KeyEntry] == "img-001", "unexpected key %v", sample[core.KeyEntry])
+	tassert.Errorf(t, string(sample["jpg"].([]byte)) == "\xff\xd8", "unexpected jpg %v", sample["jpg"])
+	tassert.Errorf(t, string(sample["cls"].([]byte)) == "3", "unexpected cls %q", sample["cls"])
+	tassert.Errorf(t, string(sample["bbox"].([]byte)) == "[0.5,1.25]", "unexpected bbox %q", sample["bbox"])
+	tassert.Errorf(t, len(sample["ids"].([]byte)) == 16 && sample["ids"].([]byte)[8] == 0x2c, "unexpected ids %v", sample["ids"])
+
+	// binary encodings are decoded back by SamplesToTFExample with corresponding types
+	decoded, err := transform.SamplesToTFExample(&singleSampleReader{sample: sample}, core.TypesMap{
+		"ids":   core.FeatureType.INT64LIST,
+		"score": core.FeatureType.FLOAT32,
+	}).Read()
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, decoded.GetFloat("score") == 0.5, "unexpected score %v", decoded.GetFloat("score"))
+
+	sample, err = r.Read()
+	tassert.CheckFatal(t, err)
+	tassert.Errorf(t, sample[core.KeyEntry] == "000000000", "expected generated key, got %v", sample[core.KeyEntry])
+	_, err = r.Read()
+	tassert.Errorf(t, err == io.EOF, "expected io.EOF, got %v", err)
+}
+
+type singleSampleReader struct {
+	sample core.Sample
+}
+
+func (r *singleSampleReader) Read() (core.Sample, error) {
+	if r.sample == nil {
+		return nil, io.EOF
+	}
+	s := r.sample
+	r.sample = nil
+	return s, nil
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sync"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/cmn"
+)
+
+// Names of compressions detected by NewReader without registration
+const (
+	CompressionGzip  = "gzip"
+	CompressionBzip2 = "bzip2"
+	CompressionZlib  = "zlib"
+)
+
+// sniffSize is number of bytes inspected to detect compression - size of TAR header block
+const sniffSize = 512
+
+type (
+	// Decompressor returns reader of data decompressed from r
+	Decompressor func(r io.Reader) (io.Reader, error)
+
+	compression struct {
+		name       string
+		match      func(header []byte) bool
+		decompress Decompressor
+	}
+)
+
+var (
+	compressionsMtx sync.RWMutex
+	compressions    = []compression{
+		{
+			name:       CompressionGzip,
+			match:      magicMatcher([]byte{0x1f, 0x8b}),
+			decompress: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		},
+		{
+			name:       CompressionBzip2,
+			match:      magicMatcher([]byte("BZh")),
+			decompress: func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil },
+		},
+		{
+			name:       CompressionZlib,
+			match:      cmn.IsZlibHeader,
+			decompress: func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+		},
+	}
+)
+
+// RegisterDecompressor makes NewReader decompress archives starting with magic bytes with d, for example
+// to support zstd or xz. If a compression with the same name is already registered, it's replaced.
+// Compressions are detected in order of registration, after the built-in ones: gzip, bzip2 and zlib.
+func RegisterDecompressor(name string, magic []byte, d Decompressor) {
+	cmn.Assert(name != "" && len(magic) > 0 && len(magic) <= sniffSize && d != nil)
+	c := compression{name: name, match: magicMatcher(append([]byte(nil), magic...)), decompress: d}
+
+	compressionsMtx.Lock()
+	defer compressionsMtx.Unlock()
+	for i := range compressions {
+		if compressions[i].name == name {
+			compressions[i] = c
+			return
+		}
+	}
+	compressions = append(compressions, c)
+}
+
+// DetectCompression returns name of compression of an archive which starts with header or empty string
+// if the archive is not compressed or compression is unknown. header should contain at least 512 bytes
+// of the archive, if available, to tell apart an uncompressed TAR.
+func DetectCompression(header []byte) string {
+	if c := detectCompression(header); c != nil {
+		return c.name
+	}
+	return ""
+}
+
+func detectCompression(header []byte) *compression {
+	if isTarHeader(header) {
+		return nil
+	}
+	compressionsMtx.RLock()
+	defer compressionsMtx.RUnlock()
+	for i := range compressions {
+		if compressions[i].match(header) {
+			c := compressions[i]
+			return &c
+		}
+	}
+	return nil
+}
+
+// NewReader creates core.SampleReader reading TAR from reader, which can be uncompressed or compressed
+// with any of registered compressions. Compression is detected based on the beginning of reader.
+// Uncompressed and gzip compressed TARs are read the same as by NewTarReader and NewTarGzReader.
+func NewReader(reader io.Reader) (core.SampleReader, error) {
+	header, reader, err := sniff(reader)
+	if err != nil {
+		return nil, err
+	}
+	c := detectCompression(header)
+	switch {
+	case c == nil:
+		return NewTarReader(reader)
+	case c.name == CompressionGzip:
+		return NewTarGzReader(reader)
+	}
+	r, err := c.decompress(reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewTarReader(r)
+}
+
+// NewStreamReader creates TarStreamReader reading TAR from reader, which can be uncompressed or compressed
+// with any of registered compressions, see NewReader and NewTarStreamReader.
+func NewStreamReader(reader io.Reader, opts ...TarStreamOptions) (*TarStreamReader, error) {
+	header, reader, err := sniff(reader)
+	if err != nil {
+		return nil, err
+	}
+	if c := detectCompression(header); c != nil {
+		if reader, err = c.decompress(reader); err != nil {
+			return nil, err
+		}
+	}
+	return NewTarStreamReader(reader, opts...), nil
+}
+
+// sniff returns the first bytes of reader and reader which reads from the beginning. If reader is
+// io.ReadSeeker it's rewound and returned as is, so it still can be read with TarSeekReader.
+func sniff(reader io.Reader) ([]byte, io.Reader, error) {
+	if rs, ok := reader.(io.ReadSeeker); ok {
+		pos, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, nil, err
+		}
+		header := make([]byte, sniffSize)
+		n, err := io.ReadFull(rs, header)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, nil, err
+		}
+		if _, err := rs.Seek(pos, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+		return header[:n], rs, nil
+	}
+
+	br := bufio.NewReaderSize(reader, sniffSize)
+	header, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	return header, br, nil
+}
+
+func magicMatcher(magic []byte) func([]byte) bool {
+	return func(header []byte) bool { return bytes.HasPrefix(header, magic) }
+}
+
+// isTarHeader checks for ustar magic of POSIX and GNU TAR headers
+func isTarHeader(header []byte) bool {
+	return len(header) >= 262 && bytes.Equal(header[257:262], []byte("ustar"))
+}
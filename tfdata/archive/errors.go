@@ -0,0 +1,44 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package archive
+
+import (
+	"fmt"
+	"io"
+)
+
+type (
+	// TarEntryError is returned by TAR readers when an entry of TAR can't be read.
+	TarEntryError struct {
+		// Offset of the entry's content in TAR (in decompressed stream, if TAR is compressed).
+		// If the entry's header can't be read, it's offset of the header.
+		Offset int64
+		// Key of the sample the entry belongs to and Member is the entry's extension.
+		// Both are empty if the entry's header can't be read.
+		Key    string
+		Member string
+		Err    error
+	}
+
+	// countingReader counts bytes read from r
+	countingReader struct {
+		r      io.Reader
+		n      int64
+		header int64 // offset of the next TAR header, maintained by nextHeader
+	}
+)
+
+func (e *TarEntryError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("TAR entry at offset %d: %v", e.Offset, e.Err)
+	}
+	return fmt.Sprintf("TAR entry %s.%s at offset %d: %v", e.Key, e.Member, e.Offset, e.Err)
+}
+
+func (e *TarEntryError) Unwrap() error { return e.Err }
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
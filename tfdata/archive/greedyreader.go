@@ -6,6 +6,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 
@@ -14,27 +15,33 @@ import (
 )
 
 func newTarGreedyReader(reader io.Reader) *TarGreedyReader {
+	counter := &countingReader{r: reader}
 	tarReader := &TarGreedyReader{
-		rm: NewRecordsManager(),
-		r:  tar.NewReader(reader),
-		ch: make(chan *sampleResult, 100),
+		rm:      NewRecordsManager(),
+		r:       tar.NewReader(counter),
+		counter: counter,
+		ch:      make(chan *sampleResult, 100),
+		done:    make(chan struct{}),
 	}
 
 	go func() {
 		defer close(tarReader.ch)
 		if err := tarReader.prepareRecords(); err != nil {
 			// prepareRecords() error will be reported on the first Read()
-			tarReader.ch <- &sampleResult{s: nil, err: err}
+			tarReader.send(&sampleResult{s: nil, err: err})
 			return
 		}
 
-		for _, r := range tarReader.rm.GetRecords() {
+		for _, name := range tarReader.order {
+			r := tarReader.rm.GetRecord(name)
 			sample := core.NewSample()
 			for k, v := range r.Members {
 				sample[k] = v
 			}
 			sample[core.KeyEntry] = r.Name
-			tarReader.ch <- &sampleResult{sample, nil}
+			if !tarReader.send(&sampleResult{sample, nil}) {
+				return
+			}
 		}
 	}()
 
@@ -46,16 +53,27 @@ func newTarGzGreedyReader(reader io.Reader) (*TarGreedyReader, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newTarGreedyReader(gzr), nil
+}
 
-	return &TarGreedyReader{
-		rm: NewRecordsManager(),
-		r:  tar.NewReader(gzr),
-	}, nil
+// send passes res to readers. Returns false if TarGreedyReader has been closed in the meantime.
+func (t *TarGreedyReader) send(res *sampleResult) bool {
+	select {
+	case t.ch <- res:
+		return true
+	case <-t.done:
+		return false
+	}
 }
 
 func (t *TarGreedyReader) prepareRecords() error {
 	for {
-		header, err := t.r.Next()
+		select {
+		case <-t.done:
+			return io.ErrClosedPipe
+		default:
+		}
+		header, err := nextHeader(t.r, t.counter)
 
 		switch {
 		case err == io.EOF:
@@ -72,22 +90,40 @@ func (t *TarGreedyReader) prepareRecords() error {
 		case tar.TypeDir:
 			continue
 		case tar.TypeReg:
+			offset := t.counter.n
 			buf := bytes.NewBuffer(make([]byte, 0, header.Size))
 			n, err := io.Copy(buf, t.r)
 			if err != nil && err != io.EOF {
-				return err
+				return &TarEntryError{Offset: offset, Key: name, Member: ext, Err: err}
 			}
 			if n != header.Size {
-				return fmt.Errorf("expected to read %d bytes, read %d instead", header.Size, n)
+				err = fmt.Errorf("expected to read %d bytes, read %d instead", header.Size, n)
+				return &TarEntryError{Offset: offset, Key: name, Member: ext, Err: err}
 			}
 
+			if t.rm.GetRecord(name) == nil {
+				t.order = append(t.order, name)
+			}
 			t.rm.UpdateRecord(name, ext, buf.Bytes()[:n])
 		}
 	}
 }
 
 func (t *TarGreedyReader) Read() (core.Sample, error) {
-	sample, ok := <-t.ch
+	return t.ReadContext(context.Background())
+}
+
+// ReadContext behaves the same as Read, but returns ctx.Err() if ctx is done before a Sample is available
+func (t *TarGreedyReader) ReadContext(ctx context.Context) (core.Sample, error) {
+	var (
+		sample *sampleResult
+		ok     bool
+	)
+	select {
+	case sample, ok = <-t.ch:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 
 	if !ok {
 		cmn.AssertMsg(sample == nil, "expected nil sample on empty and closed chanel")
@@ -97,3 +133,10 @@ func (t *TarGreedyReader) Read() (core.Sample, error) {
 	cmn.AssertMsg(sample != nil, "expected non-nil sample when chanel was not empty")
 	return sample.s, sample.err // nolint staticcheck // nil-check in the assertion above
 }
+
+// Close stops reading TAR in the background. Samples which haven't been read yet are discarded.
+// It's safe to call Close multiple times.
+func (t *TarGreedyReader) Close() error {
+	t.closeOnce.Do(func() { close(t.done) })
+	return nil
+}
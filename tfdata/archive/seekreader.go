@@ -6,6 +6,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 
@@ -17,8 +18,8 @@ func newTarSeekReader(reader io.ReadSeeker) (*TarSeekReader, error) {
 	tarReader := &TarSeekReader{
 		recordsManager:     NewRecordsManager(),
 		recordsMetaManager: NewRecordsManager(),
-		r:                  tar.NewReader(reader),
 	}
+	tarReader.reset(reader)
 
 	err := tarReader.prepareMeta()
 	if err != nil {
@@ -29,7 +30,7 @@ func newTarSeekReader(reader io.ReadSeeker) (*TarSeekReader, error) {
 	if err != nil {
 		return nil, err
 	}
-	tarReader.r = tar.NewReader(reader)
+	tarReader.reset(reader)
 
 	return tarReader, nil
 }
@@ -43,8 +44,8 @@ func newTarGzSeekReader(reader io.ReadSeeker) (*TarSeekReader, error) {
 	tarReader := &TarSeekReader{
 		recordsManager:     NewRecordsManager(),
 		recordsMetaManager: NewRecordsManager(),
-		r:                  tar.NewReader(gzr),
 	}
+	tarReader.reset(gzr)
 
 	err = tarReader.prepareMeta()
 	if err != nil {
@@ -58,14 +59,20 @@ func newTarGzSeekReader(reader io.ReadSeeker) (*TarSeekReader, error) {
 	if err != nil {
 		return nil, err
 	}
-	tarReader.r = tar.NewReader(gzr)
+	tarReader.reset(gzr)
 
 	return tarReader, err
 }
 
+// reset makes t read TAR from the beginning of reader
+func (t *TarSeekReader) reset(reader io.Reader) {
+	t.counter = &countingReader{r: reader}
+	t.r = tar.NewReader(t.counter)
+}
+
 func (t *TarSeekReader) prepareMeta() error {
 	for {
-		header, err := t.r.Next()
+		header, err := nextHeader(t.r, t.counter)
 
 		switch {
 		case err == io.EOF:
@@ -88,11 +95,20 @@ func (t *TarSeekReader) prepareMeta() error {
 }
 
 func (t *TarSeekReader) Read() (sample core.Sample, err error) {
+	return t.ReadContext(context.Background())
+}
+
+// ReadContext behaves the same as Read, but returns ctx.Err() if ctx is done before a Sample is assembled.
+// ctx is checked between TAR entries.
+func (t *TarSeekReader) ReadContext(ctx context.Context) (sample core.Sample, err error) {
 	t.mtx.Lock()
 	defer t.mtx.Unlock()
 	// iterate until first tar record is ready or EOF
 	for {
-		header, err := t.r.Next()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		header, err := nextHeader(t.r, t.counter)
 
 		switch {
 		case err == io.EOF:
@@ -111,14 +127,16 @@ func (t *TarSeekReader) Read() (sample core.Sample, err error) {
 		case tar.TypeDir:
 			continue
 		case tar.TypeReg:
+			offset := t.counter.n
 			buf := bytes.NewBuffer(make([]byte, 0, header.Size))
 			n, err := io.Copy(buf, t.r)
 
 			if err != nil && err != io.EOF {
-				return nil, err
+				return nil, &TarEntryError{Offset: offset, Key: name, Member: ext, Err: err}
 			}
 			if n != header.Size {
-				return nil, fmt.Errorf("expected to read %d bytes, read %d instead", header.Size, n)
+				err = fmt.Errorf("expected to read %d bytes, read %d instead", header.Size, n)
+				return nil, &TarEntryError{Offset: offset, Key: name, Member: ext, Err: err}
 			}
 
 			t.recordsManager.UpdateRecord(name, ext, buf.Bytes()[:n])
@@ -0,0 +1,147 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+)
+
+// ErrNotContiguous is returned by TarStreamReader with VerifyContiguous option, if entries of a sample
+// are not contiguous in TAR.
+var ErrNotContiguous = errors.New("entries of the sample are not contiguous")
+
+type (
+	// TarStreamOptions configures TarStreamReader
+	TarStreamOptions struct {
+		// VerifyContiguous makes TarStreamReader remember keys of emitted samples and return ErrNotContiguous
+		// if an entry of an already emitted sample appears. Memory used grows with number of samples then.
+		VerifyContiguous bool
+	}
+
+	// TarStreamReader reads samples from TAR in which entries of each of samples are contiguous, as in
+	// WebDataset shards. A sample is emitted as soon as an entry of the next sample is read, so only
+	// a single sample is kept in memory, regardless of TAR size, and TAR can be read from a pipe.
+	// If entries of a sample are not contiguous, they're emitted as multiple samples with the same key,
+	// unless VerifyContiguous option is set.
+	TarStreamReader struct {
+		mtx     sync.Mutex
+		r       *tar.Reader
+		counter *countingReader
+		opts    TarStreamOptions
+
+		next    core.Sample // sample assembled from entries read so far
+		nextKey string
+		emitted map[string]struct{} // keys of emitted samples, if VerifyContiguous
+		err     error               // sticky error, including io.EOF
+	}
+)
+
+var (
+	_ core.SampleReader        = &TarStreamReader{}
+	_ core.SampleReaderContext = &TarStreamReader{}
+)
+
+// NewTarStreamReader creates TarStreamReader reading TAR from reader. If opts are provided, samples
+// are read accordingly to them.
+func NewTarStreamReader(reader io.Reader, opts ...TarStreamOptions) *TarStreamReader {
+	counter := &countingReader{r: reader}
+	t := &TarStreamReader{r: tar.NewReader(counter), counter: counter}
+	if len(opts) > 0 {
+		t.opts = opts[0]
+	}
+	if t.opts.VerifyContiguous {
+		t.emitted = make(map[string]struct{})
+	}
+	return t
+}
+
+// NewTarGzStreamReader creates TarStreamReader reading TAR GZ from reader, see NewTarStreamReader.
+// Returns error if gzip header can't be read.
+func NewTarGzStreamReader(reader io.Reader, opts ...TarStreamOptions) (*TarStreamReader, error) {
+	gzr, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewTarStreamReader(gzr, opts...), nil
+}
+
+func (t *TarStreamReader) Read() (core.Sample, error) {
+	return t.ReadContext(context.Background())
+}
+
+// ReadContext behaves the same as Read, but returns ctx.Err() if ctx is done before a Sample is assembled.
+// ctx is checked between TAR entries.
+func (t *TarStreamReader) ReadContext(ctx context.Context) (core.Sample, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	for t.err == nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		header, err := nextHeader(t.r, t.counter)
+
+		switch {
+		case err == io.EOF:
+			t.err = io.EOF
+			if t.next != nil {
+				return t.emit(), nil
+			}
+			return nil, io.EOF
+		case err != nil:
+			t.err = err
+			return nil, t.err
+		case header == nil || header.Typeflag != tar.TypeReg:
+			continue
+		}
+
+		name, ext := nameExtFromHeader(header)
+		offset := t.counter.n
+		buf := bytes.NewBuffer(make([]byte, 0, header.Size))
+		n, err := io.Copy(buf, t.r)
+		if err == nil && n != header.Size {
+			err = fmt.Errorf("expected to read %d bytes, read %d instead", header.Size, n)
+		}
+		if err == nil && t.emitted != nil {
+			if _, ok := t.emitted[name]; ok {
+				err = ErrNotContiguous
+			}
+		}
+		if err != nil {
+			t.err = &TarEntryError{Offset: offset, Key: name, Member: ext, Err: err}
+			return nil, t.err
+		}
+
+		var sample core.Sample
+		if t.next != nil && t.nextKey != name {
+			sample = t.emit()
+		}
+		if t.next == nil {
+			t.next, t.nextKey = core.NewSample(), name
+			t.next[core.KeyEntry] = name
+		}
+		t.next[ext] = buf.Bytes()
+		if sample != nil {
+			return sample, nil
+		}
+	}
+	return nil, t.err
+}
+
+// emit returns the assembled sample and starts assembling the next one
+func (t *TarStreamReader) emit() core.Sample {
+	sample := t.next
+	if t.emitted != nil {
+		t.emitted[t.nextKey] = struct{}{}
+	}
+	t.next, t.nextKey = nil, ""
+	return sample
+}
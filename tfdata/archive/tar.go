@@ -28,12 +28,18 @@ type (
 		recordsManager     RecordsManager
 		recordsMetaManager RecordsManager
 		r                  *tar.Reader
+		counter            *countingReader
 	}
 
 	TarGreedyReader struct {
-		rm RecordsManager
-		r  *tar.Reader
-		ch chan *sampleResult
+		rm      RecordsManager
+		order   []string // names of records in order of appearance in TAR
+		r       *tar.Reader
+		counter *countingReader
+		ch      chan *sampleResult
+
+		done      chan struct{} // closed by Close to stop the goroutine reading TAR
+		closeOnce sync.Once
 	}
 
 	sampleResult struct {
@@ -45,6 +51,9 @@ type (
 var (
 	_ core.SampleReader = &TarGreedyReader{}
 	_ core.SampleReader = &TarSeekReader{}
+
+	_ core.SampleReaderContext = &TarGreedyReader{}
+	_ core.SampleReaderContext = &TarSeekReader{}
 )
 
 func NewTarReader(reader io.Reader) (core.SampleReader, error) {
@@ -67,3 +76,19 @@ func nameExtFromHeader(header *tar.Header) (name, ext string) {
 	ext = strings.TrimPrefix(ext, ".")
 	return name, ext
 }
+
+// nextHeader advances r to the next TAR entry, where counter counts bytes read by r. If the header can't be read,
+// it returns TarEntryError with the header's offset: tar.Reader skips the rest of the previous entry
+// before reading the header, so counter.n is already past the header's beginning when Next fails.
+func nextHeader(r *tar.Reader, counter *countingReader) (*tar.Header, error) {
+	offset := counter.header
+	header, err := r.Next()
+	if err == io.EOF {
+		return nil, err
+	}
+	if err != nil {
+		return nil, &TarEntryError{Offset: offset, Err: err}
+	}
+	counter.header = counter.n + (header.Size+tarBlockSize-1)/tarBlockSize*tarBlockSize
+	return header, nil
+}
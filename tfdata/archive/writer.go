@@ -0,0 +1,313 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/shard"
+	jsoniter "github.com/json-iterator/go"
+)
+
+var (
+	// ErrMissingKey is returned by TarWriter if a Sample doesn't have core.KeyEntry
+	ErrMissingKey = errors.New("sample doesn't have " + core.KeyEntry + " entry")
+	// ErrInvalidEntryName is returned by TarWriter if name of a Sample entry contains '/' or '.', which
+	// would make TarReader read it as a separate Sample or with a different extension
+	ErrInvalidEntryName = errors.New("sample entry name can't contain '/' or '.'")
+)
+
+const tarBlockSize = 512
+
+type (
+	// TarWriterOptions defines format of TAR written by TarWriter
+	TarWriterOptions struct {
+		// Gzip makes TarWriter write TAR GZ
+		Gzip bool
+	}
+
+	// TarShard describes a single TAR file written by TarWriter created with NewTarShardWriter
+	TarShard struct {
+		Name    string `json:"name"`
+		Samples int    `json:"samples"`
+		Bytes   int64  `json:"bytes"` // number of bytes written to the file, after compression
+	}
+
+	// TarShardOptions defines how TarWriter splits Samples into TAR shards
+	TarShardOptions struct {
+		// Pattern is a format (as in fmt package) of shard names with two integer verbs: index of a shard and
+		// total number of shards, for example "train-%06d-of-%06d.tar". Total number of shards is known
+		// only after all of them are written, so before Close shards are named with total equal to 0
+		// and renamed on Close.
+		Pattern string
+		// MaxBytes is maximum number of bytes of TAR entries (with headers) in a shard. Shard can exceed
+		// MaxBytes only if it has a single Sample. Size is counted before compression. 0 means no limit.
+		MaxBytes int64
+		// MaxSamples is maximum number of Samples in a shard. 0 means no limit.
+		MaxSamples int
+		// TarWriterOptions are used to write each of the shards.
+		TarWriterOptions TarWriterOptions
+
+		// Create creates a new shard with given name. If not provided, os.Create is used.
+		Create func(name string) (io.WriteCloser, error)
+		// Rename renames already written shard. If not provided, os.Rename is used.
+		Rename func(oldName, newName string) error
+	}
+
+	// TarWriter writes Samples to TAR, as in WebDataset shards: each of Sample entries is written
+	// as a file <key>.<entry name>, where key is value of core.KeyEntry, and entries of a Sample
+	// are contiguous. []byte and string values are written as they are, other values are marshaled to JSON.
+	// TarWriter is safe to use concurrently.
+	TarWriter struct {
+		mtx    sync.Mutex
+		opts   TarWriterOptions
+		shards *shard.Writer // nil, if not writing shards
+		err    error         // first error of Write or Close
+
+		// current TAR
+		dst io.Writer // nil, if TAR isn't open
+		gzw *gzip.Writer
+		tw  *tar.Writer
+	}
+
+	tarEntry struct {
+		name string
+		body []byte
+	}
+)
+
+var _ core.SampleWriter = &TarWriter{}
+
+// NewTarWriter creates TarWriter writing a single TAR to w. If opts are provided, TAR is written
+// accordingly to them. Close finishes TAR, but doesn't close w.
+func NewTarWriter(w io.Writer, opts ...TarWriterOptions) *TarWriter {
+	t := &TarWriter{dst: w}
+	if len(opts) > 0 {
+		t.opts = opts[0]
+	}
+	return t
+}
+
+// NewTarShardWriter creates TarWriter writing Samples into multiple TAR files, rotating them according to
+// opts. Shards are created lazily, when the first Sample of a shard is written, so no files are created
+// if nothing is written. Shards names, Samples counts and sizes are available via Shards.
+// Returns error if opts.Pattern doesn't have two integer verbs.
+func NewTarShardWriter(opts TarShardOptions) (*TarWriter, error) {
+	shards, err := shard.NewWriter(shard.Options{
+		Pattern:  opts.Pattern,
+		MaxBytes: opts.MaxBytes,
+		MaxCount: opts.MaxSamples,
+		Create:   opts.Create,
+		Rename:   opts.Rename,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TarWriter{opts: opts.TarWriterOptions, shards: shards}, nil
+}
+
+// Write writes entries of s to TAR, rotating shards before if needed. Returns ErrMissingKey
+// if s doesn't have core.KeyEntry and ErrInvalidEntryName if name of any of its entries is invalid.
+func (t *TarWriter) Write(s core.Sample) error {
+	entries, size, err := sampleEntries(s)
+	if err != nil {
+		return err
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.err != nil {
+		return t.err
+	}
+	if err := t.write(entries, size); err != nil {
+		t.err = err
+		return err
+	}
+	return nil
+}
+
+// WriteSamples reads and writes Samples from reader until io.EOF.
+func (t *TarWriter) WriteSamples(reader core.SampleReader) error {
+	for {
+		s, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := t.Write(s); err != nil {
+			return err
+		}
+	}
+}
+
+// Close finishes the current TAR. If writing shards, it closes the last shard and renames all of
+// the shards to their final names. Error, if any, is available via Err.
+func (t *TarWriter) Close() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	err := t.closeTar()
+	if err == nil && t.shards != nil {
+		err = t.shards.Close()
+	}
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+// Err returns the first error encountered by Write or Close
+func (t *TarWriter) Err() error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.err
+}
+
+// Shards returns manifest of shards written so far. Names are final only after Close.
+func (t *TarWriter) Shards() []TarShard {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.shards == nil {
+		return nil
+	}
+	infos := t.shards.Shards()
+	shards := make([]TarShard, 0, len(infos))
+	for _, info := range infos {
+		shards = append(shards, TarShard{Name: info.Name, Samples: info.Count, Bytes: info.Bytes})
+	}
+	return shards
+}
+
+func (t *TarWriter) write(entries []tarEntry, size int64) error {
+	if t.shards != nil && t.shards.ShouldRotate(size) {
+		if err := t.closeTar(); err != nil {
+			return err
+		}
+	}
+	if t.tw == nil {
+		if err := t.openTar(); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		if err := t.tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := t.tw.Write(e.body); err != nil {
+			return err
+		}
+	}
+	if t.shards != nil {
+		t.shards.Add(size)
+	}
+	return nil
+}
+
+func (t *TarWriter) openTar() error {
+	if t.shards != nil {
+		dst, err := t.shards.Open()
+		if err != nil {
+			return err
+		}
+		t.dst = dst
+	} else if t.dst == nil {
+		return errors.New("TAR writer is closed")
+	}
+
+	w := t.dst
+	if t.opts.Gzip {
+		t.gzw = gzip.NewWriter(t.dst)
+		w = t.gzw
+	}
+	t.tw = tar.NewWriter(w)
+	return nil
+}
+
+// closeTar writes TAR footer and flushes the current TAR, closing it if it's a shard
+func (t *TarWriter) closeTar() error {
+	if t.tw == nil {
+		if t.shards != nil || t.dst == nil {
+			return nil
+		}
+		// nothing written, but an empty TAR is still expected in w
+		if err := t.openTar(); err != nil {
+			return err
+		}
+	}
+
+	err := t.tw.Close()
+	if t.gzw != nil {
+		if gzErr := t.gzw.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	if t.shards != nil {
+		if closeErr := t.shards.CloseShard(); err == nil {
+			err = closeErr
+		}
+	}
+	// single TAR can be closed only once
+	t.tw, t.gzw, t.dst = nil, nil, nil
+	return err
+}
+
+// sampleEntries returns TAR entries of s, sorted by name, and their size in TAR
+func sampleEntries(s core.Sample) ([]tarEntry, int64, error) {
+	key, err := sampleKey(s)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries := make([]tarEntry, 0, len(s))
+	size := int64(0)
+	for k, v := range s {
+		if k == core.KeyEntry {
+			continue
+		}
+		if strings.ContainsAny(k, "/.") {
+			return nil, 0, fmt.Errorf("sample %s entry %s: %w", key, k, ErrInvalidEntryName)
+		}
+		body, err := entryBody(v)
+		if err != nil {
+			return nil, 0, fmt.Errorf("sample %s entry %s: %w", key, k, err)
+		}
+		entries = append(entries, tarEntry{name: key + "." + k, body: body})
+		size += tarBlockSize + (int64(len(body))+tarBlockSize-1)/tarBlockSize*tarBlockSize
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries, size, nil
+}
+
+func sampleKey(s core.Sample) (string, error) {
+	switch key := s[core.KeyEntry].(type) {
+	case string:
+		if key != "" {
+			return key, nil
+		}
+	case []byte:
+		if len(key) > 0 {
+			return string(key), nil
+		}
+	}
+	return "", ErrMissingKey
+}
+
+func entryBody(v interface{}) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case string:
+		return []byte(b), nil
+	}
+	return jsoniter.Marshal(v)
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import "github.com/NVIDIA/go-tfdata/tfdata/internal/checksum"
+
+// Checksummer computes and verifies checksums of TFRecord length headers and payloads.
+// TFRecord format uses masked CRC32C, see NewCRCChecksummer. Custom Checksummer can be provided
+// with TFRecordOptions.Checksummer, for example to use hardware-accelerated implementation.
+type Checksummer = checksum.Checksummer
+
+// ChecksumPolicy defines which checksums are verified by TFRecordReader
+type ChecksumPolicy int
+
+const (
+	// ChecksumFull - both length header and payload checksums are verified. It is the default policy.
+	ChecksumFull ChecksumPolicy = iota
+	// ChecksumHeader - only length header checksums are verified, so corrupted payloads are detected
+	// only if they can't be unmarshalled.
+	ChecksumHeader
+	// ChecksumNone - no checksums are verified. It should be used only for trusted data, for example
+	// local scratch files. Corrupted lengths are still limited by TFRecordOptions.MaxRecordSize.
+	ChecksumNone
+)
+
+// NewCRCChecksummer returns Checksummer computing masked CRC32C checksums, as required by TFRecord format.
+func NewCRCChecksummer() Checksummer {
+	return checksum.NewCRCChecksummer()
+}
+
+func (p ChecksumPolicy) verifyHeader() bool  { return p != ChecksumNone }
+func (p ChecksumPolicy) verifyPayload() bool { return p == ChecksumFull }
+
+// checksummerFrom returns Checksummer provided in opts or the default one
+func checksummerFrom(opts TFRecordOptions) Checksummer {
+	if opts.Checksummer != nil {
+		return opts.Checksummer
+	}
+	return NewCRCChecksummer()
+}
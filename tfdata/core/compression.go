@@ -0,0 +1,103 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/checksum"
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/cmn"
+)
+
+// CompressionType defines how TFRecord framing is compressed. It corresponds to compression_type argument
+// of TensorFlow's TFRecordDataset and TFRecordWriter.
+type CompressionType int
+
+const (
+	// NoCompression - TFRecord is stored as plain records framing
+	NoCompression CompressionType = iota
+	// GzipCompression - TFRecord framing is wrapped in gzip stream ("GZIP" in TensorFlow)
+	GzipCompression
+	// ZlibCompression - TFRecord framing is wrapped in zlib stream ("ZLIB" in TensorFlow)
+	ZlibCompression
+	// AutoCompression - compression is detected based on the first bytes of the input.
+	// Applicable only to TFRecordReader.
+	AutoCompression
+)
+
+func (c CompressionType) String() string {
+	switch c {
+	case NoCompression:
+		return ""
+	case GzipCompression:
+		return "GZIP"
+	case ZlibCompression:
+		return "ZLIB"
+	case AutoCompression:
+		return "AUTO"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// compressWriter wraps w with compressor according to compression. Returned io.Closer flushes
+// compressor, but it doesn't close w. If compression is NoCompression, returned io.Closer is nil.
+func compressWriter(w io.Writer, compression CompressionType) (io.Writer, io.Closer) {
+	switch compression {
+	case NoCompression:
+		return w, nil
+	case GzipCompression:
+		gzw := gzip.NewWriter(w)
+		return gzw, gzw
+	case ZlibCompression:
+		zw := zlib.NewWriter(w)
+		return zw, zw
+	default:
+		cmn.AssertMsg(false, "unsupported TFRecordWriter compression "+compression.String())
+		return nil, nil
+	}
+}
+
+// decompressReader wraps r with decompressor according to compression. If compression is AutoCompression,
+// the compression type is detected based on the first bytes read from r.
+func decompressReader(r io.Reader, compression CompressionType, c checksum.Checksummer) (io.Reader, error) {
+	if compression == AutoCompression {
+		br := bufio.NewReader(r)
+		r, compression = br, detectCompression(br, c)
+	}
+
+	switch compression {
+	case NoCompression:
+		return r, nil
+	case GzipCompression:
+		return gzip.NewReader(r)
+	case ZlibCompression:
+		return zlib.NewReader(r)
+	default:
+		cmn.AssertMsg(false, "unsupported TFRecordReader compression "+compression.String())
+		return nil, nil
+	}
+}
+
+// detectCompression peeks the beginning of the stream. If it is valid TFRecord length header the stream is
+// uncompressed, as gzip or zlib magic bytes might be as well a valid, little endian length of the first record.
+func detectCompression(r *bufio.Reader, c checksum.Checksummer) CompressionType {
+	header, _ := r.Peek(headerSize) // the error will be reported by subsequent read
+	if len(header) == headerSize && c.Verify(header[:8], binary.LittleEndian.Uint32(header[8:])) == nil {
+		return NoCompression
+	}
+	if len(header) < 2 {
+		return NoCompression
+	}
+	if header[0] == 0x1f && header[1] == 0x8b {
+		return GzipCompression
+	}
+	if cmn.IsZlibHeader(header) {
+		return ZlibCompression
+	}
+	return NoCompression
+}
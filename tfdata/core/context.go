@@ -0,0 +1,127 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import (
+	"context"
+)
+
+type (
+	// SampleReaderContext is SampleReader which can be canceled. ReadContext returns ctx.Err()
+	// if ctx is done before a Sample is available and io.EOF if there's nothing left to be read.
+	SampleReaderContext interface {
+		ReadContext(ctx context.Context) (sample Sample, err error)
+	}
+
+	// TFExampleReaderContext is TFExampleReader which can be canceled, see SampleReaderContext.
+	TFExampleReaderContext interface {
+		ReadContext(ctx context.Context) (ex *TFExample, err error)
+	}
+
+	// TFSequenceExampleReaderContext is TFSequenceExampleReader which can be canceled, see SampleReaderContext.
+	TFSequenceExampleReaderContext interface {
+		ReadContext(ctx context.Context) (ex *TFSequenceExample, err error)
+	}
+
+	sampleReaderContext struct {
+		r SampleReader
+	}
+
+	tfExampleReaderContext struct {
+		r TFExampleReader
+	}
+
+	tfSequenceExampleReaderContext struct {
+		r TFSequenceExampleReader
+	}
+
+	boundSampleReader struct {
+		ctx context.Context
+		r   SampleReaderContext
+	}
+
+	boundTFExampleReader struct {
+		ctx context.Context
+		r   TFExampleReaderContext
+	}
+
+	boundTFSequenceExampleReader struct {
+		ctx context.Context
+		r   TFSequenceExampleReaderContext
+	}
+)
+
+// NewSampleReaderContext adapts r to SampleReaderContext. If r implements SampleReaderContext, it's returned as is.
+// Otherwise ctx is checked before each of the reads, but a read which has already started isn't interrupted.
+func NewSampleReaderContext(r SampleReader) SampleReaderContext {
+	if rc, ok := r.(SampleReaderContext); ok {
+		return rc
+	}
+	return &sampleReaderContext{r: r}
+}
+
+// NewTFExampleReaderContext adapts r to TFExampleReaderContext, see NewSampleReaderContext.
+func NewTFExampleReaderContext(r TFExampleReader) TFExampleReaderContext {
+	if rc, ok := r.(TFExampleReaderContext); ok {
+		return rc
+	}
+	return &tfExampleReaderContext{r: r}
+}
+
+// NewTFSequenceExampleReaderContext adapts r to TFSequenceExampleReaderContext, see NewSampleReaderContext.
+func NewTFSequenceExampleReaderContext(r TFSequenceExampleReader) TFSequenceExampleReaderContext {
+	if rc, ok := r.(TFSequenceExampleReaderContext); ok {
+		return rc
+	}
+	return &tfSequenceExampleReaderContext{r: r}
+}
+
+// SampleReaderWithContext returns SampleReader which reads from r with ctx. It makes a cancelable reader
+// usable by stages and transformations which consume SampleReader.
+func SampleReaderWithContext(ctx context.Context, r SampleReaderContext) SampleReader {
+	return &boundSampleReader{ctx: ctx, r: r}
+}
+
+// TFExampleReaderWithContext returns TFExampleReader which reads from r with ctx, see SampleReaderWithContext.
+func TFExampleReaderWithContext(ctx context.Context, r TFExampleReaderContext) TFExampleReader {
+	return &boundTFExampleReader{ctx: ctx, r: r}
+}
+
+// TFSequenceExampleReaderWithContext returns TFSequenceExampleReader which reads from r with ctx,
+// see SampleReaderWithContext.
+func TFSequenceExampleReaderWithContext(ctx context.Context, r TFSequenceExampleReaderContext) TFSequenceExampleReader {
+	return &boundTFSequenceExampleReader{ctx: ctx, r: r}
+}
+
+func (r *sampleReaderContext) ReadContext(ctx context.Context) (Sample, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.r.Read()
+}
+
+func (r *tfExampleReaderContext) ReadContext(ctx context.Context) (*TFExample, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.r.Read()
+}
+
+func (r *tfSequenceExampleReaderContext) ReadContext(ctx context.Context) (*TFSequenceExample, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.r.Read()
+}
+
+func (r *boundSampleReader) Read() (Sample, error) {
+	return r.r.ReadContext(r.ctx)
+}
+
+func (r *boundTFExampleReader) Read() (*TFExample, error) {
+	return r.r.ReadContext(r.ctx)
+}
+
+func (r *boundTFSequenceExampleReader) Read() (*TFSequenceExample, error) {
+	return r.r.ReadContext(r.ctx)
+}
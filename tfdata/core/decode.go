@@ -0,0 +1,121 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import (
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/cmn"
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/ordered"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+type (
+	// rawRecord is a record read by TFRecordReader, which is decoded asynchronously
+	rawRecord struct {
+		payload []byte
+		offset  int64
+		index   int64
+		// corruptions skipped just before the record. If end is set, there is no record after them.
+		corruptions []TFRecordCorruption
+		end         bool
+	}
+
+	// corruptedRecord is a result of decoding rawRecord preceded by corruptions or which isn't valid TFExample.
+	// Corruptions are reported by the consumer in order of TFRecord.
+	corruptedRecord struct {
+		corruptions []TFRecordCorruption
+		ex          *TFExample // valid TFExample following corruptions, if any
+	}
+)
+
+// readDecoded returns next TFExample decoded by one of DecodeWorkers goroutines. Records are read
+// sequentially, so TFExamples are returned in order of TFRecord.
+func (r *TFRecordReader) readDecoded() (*TFExample, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	r.decoderMtx.Lock()
+	if r.decoder == nil {
+		r.frame.reuse = false // payloads are decoded after the next records are read
+		r.decoder = ordered.NewProcessor(r.readRaw, r.decode, r.opts.DecodeWorkers, orderedWindowFactor*r.opts.DecodeWorkers)
+		if r.closed {
+			r.decoder.Close()
+		}
+	}
+	decoder := r.decoder
+	r.decoderMtx.Unlock()
+
+	for {
+		result, err := decoder.Next()
+		if err != nil {
+			return nil, err
+		}
+		corrupted, ok := result.(*corruptedRecord)
+		if !ok {
+			return result.(*TFExample), nil
+		}
+		for _, corruption := range corrupted.corruptions {
+			r.reportCorruption(corruption)
+		}
+		if corrupted.ex != nil {
+			return corrupted.ex, nil
+		}
+	}
+}
+
+// readRaw reads the next record together with corruptions skipped before it. Reading records happens
+// in the goroutines of r.decoder, so corruptions are passed to readDecoded instead of being reported here.
+func (r *TFRecordReader) readRaw() (interface{}, error) {
+	if r.rawErr != nil {
+		return nil, r.rawErr
+	}
+	payload, err := r.readRecord()
+	corruptions := r.skipped
+	r.skipped = nil
+	if err != nil {
+		if len(corruptions) == 0 {
+			return nil, err
+		}
+		r.rawErr = err
+		return &rawRecord{corruptions: corruptions, end: true}, nil
+	}
+	length := headerSize + int64(len(payload)) + footerSize
+	return &rawRecord{payload: payload, offset: r.offset - length, index: r.index - 1, corruptions: corruptions}, nil
+}
+
+func (r *TFRecordReader) decode(_ int, item interface{}) (interface{}, bool, error) {
+	record := item.(*rawRecord)
+	if record.end {
+		return &corruptedRecord{corruptions: record.corruptions}, true, nil
+	}
+	ex := &TFExample{}
+	err := protobuf.Unmarshal(record.payload, ex)
+	if err == nil {
+		if len(record.corruptions) > 0 {
+			return &corruptedRecord{corruptions: record.corruptions, ex: ex}, true, nil
+		}
+		return ex, true, nil
+	}
+	err = &UnmarshalError{Offset: record.offset, Index: record.index, Err: err}
+	if r.opts.Corruption != CorruptionSkip {
+		return nil, false, err
+	}
+	corruption := TFRecordCorruption{Offset: record.offset, Length: headerSize + int64(len(record.payload)) + footerSize, Index: record.index, Err: err}
+	return &corruptedRecord{corruptions: append(record.corruptions, corruption)}, true, nil
+}
+
+// Close stops decoding goroutines if TFRecordReader was created with DecodeWorkers. It doesn't close
+// the underlying reader. TFRecordReader can't be used after Close.
+func (r *TFRecordReader) Close() error {
+	r.decoderMtx.Lock()
+	defer r.decoderMtx.Unlock()
+	r.closed = true
+	if r.decoder != nil {
+		r.decoder.Close()
+	}
+	return nil
+}
+
+// assertNotDecoding asserts that TFRecordReader doesn't read records for DecodeWorkers
+func (r *TFRecordReader) assertNotDecoding() {
+	cmn.AssertMsg(r.opts.DecodeWorkers == 0, "TFRecordReader with DecodeWorkers can't be read with other methods than Read")
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/checksum"
+)
+
+// Errors returned by TFRecordReader, TFRecordRandomReader and BuildTFRecordIndex describe position of the invalid
+// record, so they can be inspected with errors.As. Offset is offset of the record's length header in TFRecord
+// (in decompressed stream, if TFRecord is compressed) and Index is number of records preceding it.
+
+type (
+	// InvalidChecksumError describes checksums mismatch. It is wrapped by HeaderChecksumError and PayloadChecksumError.
+	InvalidChecksumError = checksum.InvalidChecksumError
+
+	// HeaderChecksumError is returned when checksum of record's length header is invalid
+	HeaderChecksumError struct {
+		Offset int64
+		Index  int64
+		Err    error
+	}
+
+	// PayloadChecksumError is returned when checksum of record's payload is invalid
+	PayloadChecksumError struct {
+		Offset int64
+		Index  int64
+		Err    error
+	}
+
+	// TruncatedRecordError is returned when TFRecord ends in the middle of a record.
+	// It wraps io.ErrUnexpectedEOF.
+	TruncatedRecordError struct {
+		Offset int64
+		Index  int64
+		// Size is expected size of the record or 0 if the length header is truncated
+		Size int64
+		// Read is number of bytes of the record available in TFRecord
+		Read int64
+	}
+
+	// OversizedRecordError is returned when length header of a record exceeds the limit
+	OversizedRecordError struct {
+		Offset int64
+		Index  int64
+		Length uint64
+		Limit  uint64
+	}
+
+	// UnmarshalError is returned when record's payload is not a valid protobuf message
+	UnmarshalError struct {
+		Offset int64
+		Index  int64
+		Err    error
+	}
+)
+
+// ErrIndexOutOfRange is returned by TFRecordRandomReader when requested record index is not in the index
+var ErrIndexOutOfRange = errors.New("index out of range")
+
+func (e *HeaderChecksumError) Error() string {
+	return fmt.Sprintf("TFRecord record %d at offset %d: invalid length header: %v", e.Index, e.Offset, e.Err)
+}
+
+func (e *HeaderChecksumError) Unwrap() error { return e.Err }
+
+func (e *PayloadChecksumError) Error() string {
+	return fmt.Sprintf("TFRecord record %d at offset %d: invalid payload: %v", e.Index, e.Offset, e.Err)
+}
+
+func (e *PayloadChecksumError) Unwrap() error { return e.Err }
+
+func (e *TruncatedRecordError) Error() string {
+	if e.Size == 0 {
+		return fmt.Sprintf("TFRecord record %d at offset %d: truncated length header, read %d bytes", e.Index, e.Offset, e.Read)
+	}
+	return fmt.Sprintf("TFRecord record %d at offset %d: truncated record, expected %d bytes, read %d", e.Index, e.Offset, e.Size, e.Read)
+}
+
+func (e *TruncatedRecordError) Unwrap() error { return io.ErrUnexpectedEOF }
+
+func (e *OversizedRecordError) Error() string {
+	return fmt.Sprintf("TFRecord record %d at offset %d: payload length %d exceeds limit %d", e.Index, e.Offset, e.Length, e.Limit)
+}
+
+func (e *UnmarshalError) Error() string {
+	return fmt.Sprintf("TFRecord record %d at offset %d: %v", e.Index, e.Offset, e.Err)
+}
+
+func (e *UnmarshalError) Unwrap() error { return e.Err }
+
+// Errors returned by TFExample Lookup* accessors, see LookupFeature.
+
+type (
+	// MissingFeatureError is returned when TFExample doesn't contain requested feature
+	MissingFeatureError struct {
+		Feature string
+	}
+
+	// FeatureKindError is returned when requested feature is of other kind than expected
+	FeatureKindError struct {
+		Feature  string
+		Expected string
+		Actual   string
+	}
+
+	// FeatureCardinalityError is returned when requested feature contains other number of values than expected
+	FeatureCardinalityError struct {
+		Feature  string
+		Expected int
+		Actual   int
+	}
+)
+
+func (e *MissingFeatureError) Error() string {
+	return fmt.Sprintf("feature %q not found", e.Feature)
+}
+
+func (e *FeatureKindError) Error() string {
+	return fmt.Sprintf("feature %q: expected %s list, got %s list", e.Feature, e.Expected, e.Actual)
+}
+
+func (e *FeatureCardinalityError) Error() string {
+	return fmt.Sprintf("feature %q: expected %d values, got %d", e.Feature, e.Expected, e.Actual)
+}
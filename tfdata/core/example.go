@@ -114,6 +114,8 @@ func (e *TFExample) AddFloat(name string, floats ...float32) {
 	e.AddFloatList(name, floats)
 }
 
+// GetBytesList returns the only value of the bytes feature name. It panics if the feature doesn't contain
+// exactly one value, see LookupBytes and LookupBytesList for error-returning alternatives.
 func (e *TFExample) GetBytesList(name string) []byte {
 	f := e.GetFeature(name).GetBytesList().Value
 	cmn.Assert(len(f) == 1)
@@ -0,0 +1,257 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/checksum"
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/cmn"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// TFRecord index is a text file, where each line describes a single record of TFRecord file: "offset size\n".
+// Offset is position of the record's length header in TFRecord file and size is a number of bytes
+// occupied by the whole record: length header, payload and payload checksum.
+// The format is compatible with NVIDIA DALI tfrecord2idx script.
+
+type (
+	// TFRecordIndexEntry describes position of a single record in TFRecord file
+	TFRecordIndexEntry struct {
+		Offset int64
+		Size   int64
+	}
+
+	// TFRecordIndex is a list of records positions in TFRecord file, in order of occurrence
+	TFRecordIndex []TFRecordIndexEntry
+
+	// TFRecordIndexWriter writes TFRecordIndexEntry in text format to writer w
+	TFRecordIndexWriter struct {
+		w io.Writer
+	}
+
+	// TFRecordRandomReader reads records from TFRecord at arbitrary positions, based on TFRecordIndex.
+	// It doesn't hold any state apart from the index, so it is safe to use it concurrently, as long as
+	// underlying io.ReaderAt is.
+	TFRecordRandomReader struct {
+		r      io.ReaderAt
+		c      checksum.Checksummer
+		policy ChecksumPolicy
+		index  TFRecordIndex
+	}
+)
+
+// payload checksum size
+const footerSize = 4
+
+func NewTFRecordIndexWriter(w io.Writer) *TFRecordIndexWriter {
+	return &TFRecordIndexWriter{w: w}
+}
+
+// Write writes single index entry to the underlying writer
+func (w *TFRecordIndexWriter) Write(entry TFRecordIndexEntry) error {
+	_, err := fmt.Fprintf(w.w, "%d %d\n", entry.Offset, entry.Size)
+	return err
+}
+
+// ReadTFRecordIndex reads TFRecordIndex in text format from r until EOF. Returns error if entries
+// overlap, aren't in order of offsets or are too small to contain a record.
+func ReadTFRecordIndex(r io.Reader) (TFRecordIndex, error) {
+	var (
+		index   = make(TFRecordIndex, 0, 20)
+		scanner = bufio.NewScanner(r)
+		line    = 0
+	)
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry TFRecordIndexEntry
+		if _, err := fmt.Sscanf(scanner.Text(), "%d %d", &entry.Offset, &entry.Size); err != nil {
+			return nil, fmt.Errorf("invalid TFRecord index entry in line %d: %v", line, err)
+		}
+		if err := index.checkNext(entry); err != nil {
+			return nil, fmt.Errorf("invalid TFRecord index entry in line %d: %v", line, err)
+		}
+		index = append(index, entry)
+	}
+	return index, scanner.Err()
+}
+
+// BuildTFRecordIndex reads uncompressed TFRecord from r and writes its index to w. Records length headers
+// checksums are verified with Checksummer and Checksum policy of opts, if provided, however payloads are skipped
+// without reading them to memory. If r is io.Seeker, payloads are skipped with Seek instead of read.
+func BuildTFRecordIndex(r io.Reader, w io.Writer, opts ...TFRecordOptions) error {
+	var (
+		o           = optionsFrom(opts)
+		c           checksum.Checksummer
+		indexWriter = NewTFRecordIndexWriter(w)
+		offset      int64
+	)
+	cmn.AssertMsg(o.Compression == NoCompression, "TFRecord index can't be built from compressed TFRecord")
+	if o.Checksum.verifyHeader() {
+		c = checksummerFrom(o)
+	}
+	size := remainingSize(r)
+	seeker, isSeeker := r.(io.Seeker)
+	isSeeker = isSeeker && size >= 0
+
+	header := make([]byte, headerSize)
+	for index := int64(0); ; index++ {
+		payloadLength, err := readHeader(r, c, header, offset, index, maxPayloadLength)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		skip := int64(payloadLength) + footerSize
+		if isSeeker {
+			if offset+headerSize+skip > size {
+				return &TruncatedRecordError{Offset: offset, Index: index, Size: headerSize + skip, Read: size - offset}
+			}
+			_, err = seeker.Seek(skip, io.SeekCurrent)
+		} else {
+			var n int64
+			n, err = io.CopyN(ioutil.Discard, r, skip)
+			if err == io.EOF && n < skip {
+				err = &TruncatedRecordError{Offset: offset, Index: index, Size: headerSize + skip, Read: headerSize + n}
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		entry := TFRecordIndexEntry{Offset: offset, Size: headerSize + skip}
+		if err := indexWriter.Write(entry); err != nil {
+			return err
+		}
+		offset += entry.Size
+	}
+}
+
+// checkNext returns error if entry can't follow the last entry of index
+func (index TFRecordIndex) checkNext(entry TFRecordIndexEntry) error {
+	if entry.Offset < 0 || entry.Size < headerSize+footerSize {
+		return fmt.Errorf("invalid offset %d or size %d", entry.Offset, entry.Size)
+	}
+	if len(index) > 0 {
+		if prev := index[len(index)-1]; entry.Offset < prev.Offset+prev.Size {
+			return fmt.Errorf("offset %d overlaps previous record [%d, %d)", entry.Offset, prev.Offset, prev.Offset+prev.Size)
+		}
+	}
+	return nil
+}
+
+// NewTFRecordRandomReader creates TFRecordRandomReader reading uncompressed TFRecord from r
+// at positions described by index. If opts are provided, checksums are verified accordingly to them.
+func NewTFRecordRandomReader(r io.ReaderAt, index TFRecordIndex, opts ...TFRecordOptions) *TFRecordRandomReader {
+	o := optionsFrom(opts)
+	cmn.AssertMsg(o.Compression == NoCompression, "TFRecordRandomReader can't read compressed TFRecord")
+	return &TFRecordRandomReader{r: r, c: checksummerFrom(o), policy: o.Checksum, index: index}
+}
+
+// Len returns number of records in TFRecord.
+func (r *TFRecordRandomReader) Len() int {
+	return len(r.index)
+}
+
+// ReadRecordAt reads i-th record and returns its raw payload. Returns error wrapping ErrIndexOutOfRange
+// if i is not in the index.
+func (r *TFRecordRandomReader) ReadRecordAt(i int) ([]byte, error) {
+	if i < 0 || i >= len(r.index) {
+		return nil, fmt.Errorf("TFRecord record %d: %w, TFRecord has %d records", i, ErrIndexOutOfRange, len(r.index))
+	}
+	entry := r.index[i]
+	return r.readEntry(io.NewSectionReader(r.r, entry.Offset, entry.Size), i)
+}
+
+// readEntry reads i-th record from src, which contains bytes of the record
+func (r *TFRecordRandomReader) readEntry(src io.Reader, i int) ([]byte, error) {
+	entry := r.index[i]
+	f := frameReader{c: r.c, policy: r.policy, limits: recordLimits{maxLength: maxPayloadLength, end: entry.Offset + entry.Size}}
+	payload, _, _, err := f.read(src, entry.Offset, int64(i))
+	if err == io.EOF {
+		err = &TruncatedRecordError{Offset: entry.Offset, Index: int64(i), Size: entry.Size}
+	}
+	return payload, err
+}
+
+// ReadMessageAt reads i-th record and stores it in provided message.
+func (r *TFRecordRandomReader) ReadMessageAt(i int, message protobuf.Message) error {
+	payload, err := r.ReadRecordAt(i)
+	if err != nil {
+		return err
+	}
+	entry := r.index[i]
+	if err := protobuf.Unmarshal(payload, message); err != nil {
+		return &UnmarshalError{Offset: entry.Offset, Index: int64(i), Err: err}
+	}
+	return nil
+}
+
+// ReadExampleAt reads i-th TFExample from TFRecord.
+func (r *TFRecordRandomReader) ReadExampleAt(i int) (*TFExample, error) {
+	ex := &TFExample{}
+	return ex, r.ReadMessageAt(i, ex)
+}
+
+// ReadExamplesRange reads TFExamples with indices from range [from, to). Records are read from the underlying
+// io.ReaderAt with a single read call. Returns error wrapping ErrIndexOutOfRange if the range is invalid.
+func (r *TFRecordRandomReader) ReadExamplesRange(from, to int) ([]*TFExample, error) {
+	if from < 0 || from > to || to > len(r.index) {
+		return nil, fmt.Errorf("TFRecord records range [%d, %d): %w, TFRecord has %d records", from, to, ErrIndexOutOfRange, len(r.index))
+	}
+	if from == to {
+		return []*TFExample{}, nil
+	}
+
+	// index might have been created without ReadTFRecordIndex, so it's validated before being trusted
+	for i := from; i < to; i++ {
+		if err := r.index[from:i].checkNext(r.index[i]); err != nil {
+			return nil, fmt.Errorf("invalid TFRecord index entry %d: %v", i, err)
+		}
+	}
+
+	var (
+		first, last = r.index[from], r.index[to-1]
+		buf         = make([]byte, last.Offset+last.Size-first.Offset)
+		result      = make([]*TFExample, 0, to-from)
+	)
+	if n, err := r.r.ReadAt(buf, first.Offset); n < len(buf) {
+		if err == io.EOF {
+			// find the first record which is not fully available
+			i := from
+			for i < to-1 && r.index[i].Offset+r.index[i].Size <= first.Offset+int64(n) {
+				i++
+			}
+			entry, read := r.index[i], first.Offset+int64(n)-r.index[i].Offset
+			if read < 0 {
+				read = 0
+			}
+			err = &TruncatedRecordError{Offset: entry.Offset, Index: int64(i), Size: entry.Size, Read: read}
+		}
+		return nil, err
+	}
+
+	for i := from; i < to; i++ {
+		entry := r.index[i]
+		start := entry.Offset - first.Offset
+		payload, err := r.readEntry(bytes.NewReader(buf[start:start+entry.Size]), i)
+		if err != nil {
+			return nil, err
+		}
+		ex := &TFExample{}
+		if err := protobuf.Unmarshal(payload, ex); err != nil {
+			return nil, &UnmarshalError{Offset: entry.Offset, Index: int64(i), Err: err}
+		}
+		result = append(result, ex)
+	}
+	return result, nil
+}
@@ -0,0 +1,371 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/NVIDIA/go-tfdata/proto"
+	"google.golang.org/protobuf/encoding/prototext"
+)
+
+// JSON form of TFExample annotates each of features with its kind:
+//
+//	{"features": {"label": {"int64": [1]}, "bbox": {"float": [0.1, 0.5]}, "name": {"bytes": ["cat.jpg"]},
+//	 "image": {"bytes": [{"base64": "/9j/4AAQ..."}]}, "empty": {}}}
+//
+// Bytes which are valid UTF-8 are represented as strings, other bytes as objects with base64 field.
+// NaN and infinite floats are represented as strings "NaN", "Infinity" and "-Infinity".
+
+// MaxJSONLLineSize is maximum length of a line read by JSONLReader. Lines can be long, as they contain
+// whole TFExamples, often with base64 encoded images.
+const MaxJSONLLineSize = 256 << 20
+
+type (
+	jsonExample struct {
+		Features map[string]*jsonFeature `json:"features"`
+	}
+
+	// jsonFeature has at most one of the fields set. Empty lists are kept, so they're pointers.
+	jsonFeature struct {
+		Int64 *[]int64     `json:"int64,omitempty"`
+		Float *[]jsonFloat `json:"float,omitempty"`
+		Bytes *[]jsonBytes `json:"bytes,omitempty"`
+	}
+
+	jsonFloat float32
+
+	jsonBytes []byte
+
+	jsonBase64 struct {
+		Base64 string `json:"base64"`
+	}
+
+	// JSONLReader reads TFExamples from JSON Lines, one TFExample in JSON form per line. It's async-safe.
+	JSONLReader struct {
+		mtx     sync.Mutex
+		scanner *bufio.Scanner
+		line    int // number of lines read so far
+	}
+
+	// JSONLWriter writes TFExamples as JSON Lines, one TFExample in JSON form per line. It's async-safe.
+	JSONLWriter struct {
+		mtx sync.Mutex
+		w   io.Writer
+	}
+)
+
+var (
+	_ TFExampleReader = &JSONLReader{}
+	_ TFExampleWriter = &JSONLWriter{}
+)
+
+// MarshalJSON returns JSON form of e.
+func (e *TFExample) MarshalJSON() ([]byte, error) {
+	features := e.GetFeatures().GetFeature()
+	je := jsonExample{Features: make(map[string]*jsonFeature, len(features))}
+	for name, feature := range features {
+		jf := &jsonFeature{}
+		switch kind := feature.GetKind().(type) {
+		case *proto.Feature_Int64List:
+			values := append(make([]int64, 0, len(kind.Int64List.GetValue())), kind.Int64List.GetValue()...)
+			jf.Int64 = &values
+		case *proto.Feature_FloatList:
+			values := make([]jsonFloat, 0, len(kind.FloatList.GetValue()))
+			for _, v := range kind.FloatList.GetValue() {
+				values = append(values, jsonFloat(v))
+			}
+			jf.Float = &values
+		case *proto.Feature_BytesList:
+			values := make([]jsonBytes, 0, len(kind.BytesList.GetValue()))
+			for _, v := range kind.BytesList.GetValue() {
+				values = append(values, jsonBytes(v))
+			}
+			jf.Bytes = &values
+		}
+		je.Features[name] = jf
+	}
+	return json.Marshal(je)
+}
+
+// UnmarshalJSON replaces features of e with ones from JSON form in b.
+func (e *TFExample) UnmarshalJSON(b []byte) error {
+	var je jsonExample
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&je); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after TFExample")
+	}
+
+	features := make(map[string]*proto.Feature, len(je.Features))
+	for name, jf := range je.Features {
+		if jf == nil {
+			return fmt.Errorf("feature %q: null feature", name)
+		}
+		feature := &proto.Feature{}
+		set := 0
+		if jf.Int64 != nil {
+			set++
+			feature.Kind = &proto.Feature_Int64List{Int64List: &proto.Int64List{Value: *jf.Int64}}
+		}
+		if jf.Float != nil {
+			set++
+			values := make([]float32, 0, len(*jf.Float))
+			for _, v := range *jf.Float {
+				values = append(values, float32(v))
+			}
+			feature.Kind = &proto.Feature_FloatList{FloatList: &proto.FloatList{Value: values}}
+		}
+		if jf.Bytes != nil {
+			set++
+			values := make([][]byte, 0, len(*jf.Bytes))
+			for _, v := range *jf.Bytes {
+				values = append(values, []byte(v))
+			}
+			feature.Kind = &proto.Feature_BytesList{BytesList: &proto.BytesList{Value: values}}
+		}
+		if set > 1 {
+			return fmt.Errorf("feature %q: expected at most one of int64, float and bytes lists", name)
+		}
+		features[name] = feature
+	}
+	e.Example.Reset()
+	e.Features = &proto.Features{Feature: features}
+	return nil
+}
+
+// MarshalTextProto returns e in protobuf text format (pbtxt), laid out the same as by TensorFlow.
+// Features are sorted by name, so the output is stable and can be compared. prototext isn't used,
+// because it deliberately randomizes whitespaces of its output.
+func (e *TFExample) MarshalTextProto() ([]byte, error) {
+	features := e.GetFeatures().GetFeature()
+	names := make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("features {\n")
+	for _, name := range names {
+		buf.WriteString("  feature {\n    key: ")
+		writeTextBytes(buf, []byte(name))
+		buf.WriteString("\n    value {\n")
+		switch kind := features[name].GetKind().(type) {
+		case *proto.Feature_Int64List:
+			buf.WriteString("      int64_list {\n")
+			for _, v := range kind.Int64List.GetValue() {
+				buf.WriteString("        value: ")
+				buf.WriteString(strconv.FormatInt(v, 10))
+				buf.WriteByte('\n')
+			}
+			buf.WriteString("      }\n")
+		case *proto.Feature_FloatList:
+			buf.WriteString("      float_list {\n")
+			for _, v := range kind.FloatList.GetValue() {
+				buf.WriteString("        value: ")
+				buf.WriteString(formatTextFloat(v))
+				buf.WriteByte('\n')
+			}
+			buf.WriteString("      }\n")
+		case *proto.Feature_BytesList:
+			buf.WriteString("      bytes_list {\n")
+			for _, v := range kind.BytesList.GetValue() {
+				buf.WriteString("        value: ")
+				writeTextBytes(buf, v)
+				buf.WriteByte('\n')
+			}
+			buf.WriteString("      }\n")
+		}
+		buf.WriteString("    }\n  }\n")
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+// UnmarshalTextProto replaces e with TFExample in protobuf text format (pbtxt) from b.
+func (e *TFExample) UnmarshalTextProto(b []byte) error {
+	if err := prototext.Unmarshal(b, &e.Example); err != nil {
+		return err
+	}
+	if e.Features == nil {
+		e.Features = &proto.Features{}
+	}
+	if e.Features.Feature == nil {
+		e.Features.Feature = make(map[string]*proto.Feature)
+	}
+	return nil
+}
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	switch {
+	case math.IsNaN(v):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(v, 1):
+		return []byte(`"Infinity"`), nil
+	case math.IsInf(v, -1):
+		return []byte(`"-Infinity"`), nil
+	}
+	return strconv.AppendFloat(nil, v, 'g', -1, 32), nil
+}
+
+func (f *jsonFloat) UnmarshalJSON(b []byte) error {
+	var s string
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		switch s {
+		case "NaN":
+			*f = jsonFloat(math.NaN())
+		case "Infinity":
+			*f = jsonFloat(math.Inf(1))
+		case "-Infinity":
+			*f = jsonFloat(math.Inf(-1))
+		default:
+			return fmt.Errorf("invalid float value %q", s)
+		}
+		return nil
+	}
+	v, err := strconv.ParseFloat(string(b), 32)
+	if err != nil {
+		return err
+	}
+	*f = jsonFloat(v)
+	return nil
+}
+
+func (b jsonBytes) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(jsonBase64{Base64: base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *jsonBytes) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*b = []byte(s)
+		return nil
+	}
+	var encoded jsonBase64
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+func formatTextFloat(f float32) string {
+	v := float64(f)
+	switch {
+	case math.IsNaN(v):
+		return "nan"
+	case math.IsInf(v, 1):
+		return "inf"
+	case math.IsInf(v, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 32)
+}
+
+// writeTextBytes writes quoted b, escaping non-printable bytes with octal escapes, like C-escaping in TensorFlow
+func writeTextBytes(buf *bytes.Buffer, b []byte) {
+	buf.WriteByte('"')
+	for _, c := range b {
+		switch c {
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '"', '\'', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(buf, "\\%03o", c)
+			} else {
+				buf.WriteByte(c)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// JSON Lines
+
+// NewJSONLReader creates JSONLReader reading TFExamples from r. Each of non-empty lines of r has to contain
+// exactly one TFExample in JSON form, at most MaxJSONLLineSize bytes long.
+func NewJSONLReader(r io.Reader) *JSONLReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxJSONLLineSize)
+	return &JSONLReader{scanner: scanner}
+}
+
+// Read returns the next TFExample or io.EOF if there's nothing left to be read. Empty lines are skipped.
+func (r *JSONLReader) Read() (*TFExample, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for r.scanner.Scan() {
+		r.line++
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		ex := NewTFExample()
+		if err := ex.UnmarshalJSON(line); err != nil {
+			return nil, fmt.Errorf("JSONL line %d: %w", r.line, err)
+		}
+		return ex, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("JSONL line %d: %w", r.line+1, err)
+	}
+	return nil, io.EOF
+}
+
+// NewJSONLWriter creates JSONLWriter writing TFExamples to w, each of them in a single line.
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	return &JSONLWriter{w: w}
+}
+
+// Write writes ex in JSON form followed by a newline with a single write to the underlying writer.
+func (w *JSONLWriter) Write(ex *TFExample) error {
+	b, err := ex.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	_, err = w.w.Write(append(b, '\n'))
+	return err
+}
+
+// Close doesn't close the underlying writer, it's present to implement TFExampleWriter.
+func (w *JSONLWriter) Close() {}
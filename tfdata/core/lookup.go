@@ -0,0 +1,135 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import (
+	"bytes"
+	"image"
+
+	"github.com/NVIDIA/go-tfdata/proto"
+)
+
+// Lookup* accessors never panic, so they are safe to use with TFExamples from untrusted sources.
+// They return *MissingFeatureError if TFExample doesn't contain a feature, *FeatureKindError if the feature
+// is of other kind than requested and *FeatureCardinalityError if a single value is requested,
+// but the feature contains other number of values. A feature without kind set is treated as an empty list.
+
+// Kinds of features reported by FeatureKindError
+const (
+	FeatureKindBytes = "bytes"
+	FeatureKindFloat = "float"
+	FeatureKindInt64 = "int64"
+	FeatureKindNone  = "none"
+)
+
+// LookupFeature returns the feature name or *MissingFeatureError if e doesn't contain it.
+func (e *TFExample) LookupFeature(name string) (*proto.Feature, error) {
+	feature, ok := e.GetFeatures().GetFeature()[name]
+	if !ok || feature == nil {
+		return nil, &MissingFeatureError{Feature: name}
+	}
+	return feature, nil
+}
+
+// LookupInt64List returns all values of the int64 feature name.
+func (e *TFExample) LookupInt64List(name string) ([]int64, error) {
+	feature, err := e.lookupKind(name, FeatureKindInt64)
+	if err != nil {
+		return nil, err
+	}
+	return feature.GetInt64List().GetValue(), nil
+}
+
+// LookupInt64 returns the only value of the int64 feature name.
+func (e *TFExample) LookupInt64(name string) (int64, error) {
+	values, err := e.LookupInt64List(name)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) != 1 {
+		return 0, &FeatureCardinalityError{Feature: name, Expected: 1, Actual: len(values)}
+	}
+	return values[0], nil
+}
+
+// LookupFloatList returns all values of the float feature name.
+func (e *TFExample) LookupFloatList(name string) ([]float32, error) {
+	feature, err := e.lookupKind(name, FeatureKindFloat)
+	if err != nil {
+		return nil, err
+	}
+	return feature.GetFloatList().GetValue(), nil
+}
+
+// LookupFloat returns the only value of the float feature name.
+func (e *TFExample) LookupFloat(name string) (float32, error) {
+	values, err := e.LookupFloatList(name)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) != 1 {
+		return 0, &FeatureCardinalityError{Feature: name, Expected: 1, Actual: len(values)}
+	}
+	return values[0], nil
+}
+
+// LookupBytesList returns all values of the bytes feature name.
+func (e *TFExample) LookupBytesList(name string) ([][]byte, error) {
+	feature, err := e.lookupKind(name, FeatureKindBytes)
+	if err != nil {
+		return nil, err
+	}
+	return feature.GetBytesList().GetValue(), nil
+}
+
+// LookupBytes returns the only value of the bytes feature name.
+func (e *TFExample) LookupBytes(name string) ([]byte, error) {
+	values, err := e.LookupBytesList(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 {
+		return nil, &FeatureCardinalityError{Feature: name, Expected: 1, Actual: len(values)}
+	}
+	return values[0], nil
+}
+
+// LookupString returns the only value of the bytes feature name as string.
+func (e *TFExample) LookupString(name string) (string, error) {
+	b, err := e.LookupBytes(name)
+	return string(b), err
+}
+
+// LookupImage decodes an image from the only value of the bytes feature name, see GetImage.
+func (e *TFExample) LookupImage(name string) (image.Image, error) {
+	b, err := e.LookupBytes(name)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	return img, err
+}
+
+func (e *TFExample) lookupKind(name, kind string) (*proto.Feature, error) {
+	feature, err := e.LookupFeature(name)
+	if err != nil {
+		return nil, err
+	}
+	if actual := featureKind(feature); actual != kind && actual != FeatureKindNone {
+		return nil, &FeatureKindError{Feature: name, Expected: kind, Actual: actual}
+	}
+	return feature, nil
+}
+
+func featureKind(feature *proto.Feature) string {
+	switch feature.GetKind().(type) {
+	case *proto.Feature_BytesList:
+		return FeatureKindBytes
+	case *proto.Feature_FloatList:
+		return FeatureKindFloat
+	case *proto.Feature_Int64List:
+		return FeatureKindInt64
+	default:
+		return FeatureKindNone
+	}
+}
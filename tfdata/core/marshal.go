@@ -0,0 +1,487 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/NVIDIA/go-tfdata/proto"
+)
+
+// MarshalExample and UnmarshalExample map fields of Go structs to features of TFExample according to
+// struct tags of the form `tfexample:"name,kind,omitempty"`, for example:
+//
+//	type Record struct {
+//		Label  int         `tfexample:"label"`
+//		Score  float64     `tfexample:"score,omitempty"`
+//		Class  int         `tfexample:"class,float"` // stored as float feature
+//		Tags   []string    `tfexample:"tags"`
+//		Image  image.Image `tfexample:"image"`
+//		BBox   BBox        `tfexample:"image/bbox"` // fields of BBox are stored as image/bbox/<name>
+//		Cached []byte      `tfexample:"-"`          // ignored
+//	}
+//
+// name defaults to the name of a field and kind - one of int64, float and bytes - to the kind natural for
+// field's type: integers and bools are int64, floats are float and strings, []byte and image.Image are bytes.
+// Integers and bools can be stored as float, if requested by kind. Slices are stored as lists of values,
+// other types as single values. Fields of nested structs are stored with name of the struct field
+// and "/" as a prefix, fields of embedded structs without tag are stored as fields of the outer struct.
+// Images are stored PNG encoded. omitempty skips zero values. Nil pointers are always skipped.
+
+const exampleTag = "tfexample"
+
+type exampleField struct {
+	name      string
+	kind      string // empty if not specified
+	omitEmpty bool
+}
+
+var imageType = reflect.TypeOf((*image.Image)(nil)).Elem()
+
+// MarshalExample returns TFExample with features from fields of v, which has to be a struct or a pointer to struct.
+func MarshalExample(v interface{}) (*TFExample, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("tfexample: expected struct or pointer to struct, got %T", v)
+	}
+	ex := NewTFExample()
+	if err := marshalStruct(ex, rv, ""); err != nil {
+		return nil, err
+	}
+	return ex, nil
+}
+
+// UnmarshalExample sets fields of struct pointed by v with features of ex. Fields of missing features are left
+// unchanged. Returns error wrapping *FeatureKindError or *FeatureCardinalityError if a feature doesn't match its field.
+func UnmarshalExample(ex *TFExample, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("tfexample: expected non-nil pointer to struct, got %T", v)
+	}
+	return unmarshalStruct(ex, rv.Elem(), "")
+}
+
+func parseExampleTag(sf reflect.StructField) (f exampleField, ok bool) {
+	tag := sf.Tag.Get(exampleTag)
+	if tag == "-" {
+		return f, false
+	}
+	parts := strings.Split(tag, ",")
+	f.name = parts[0]
+	if f.name == "" {
+		f.name = sf.Name
+	}
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "omitempty":
+			f.omitEmpty = true
+		default:
+			f.kind = opt
+		}
+	}
+	return f, true
+}
+
+// nestedStruct returns true if values of t are stored as separate features
+func nestedStruct(t reflect.Type) bool {
+	if t.Implements(imageType) {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !reflect.PtrTo(t).Implements(imageType)
+}
+
+// fieldPrefix returns prefix of features of nested struct field
+func fieldPrefix(sf reflect.StructField, f exampleField, prefix string) string {
+	if sf.Anonymous && sf.Tag.Get(exampleTag) == "" {
+		return prefix
+	}
+	return prefix + f.name + "/"
+}
+
+func marshalStruct(ex *TFExample, rv reflect.Value, prefix string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous { // unexported
+			continue
+		}
+		f, ok := parseExampleTag(sf)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+		if nestedStruct(sf.Type) {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if err := marshalStruct(ex, fv, fieldPrefix(sf, f, prefix)); err != nil {
+				return err
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if fv.Kind() == reflect.Ptr && !sf.Type.Implements(imageType) {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if (fv.Kind() == reflect.Interface || fv.Kind() == reflect.Ptr) && fv.IsNil() {
+			continue
+		}
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		feature, err := marshalValue(fv, f.kind)
+		if err != nil {
+			return fmt.Errorf("tfexample: field %s: %v", sf.Name, err)
+		}
+		ex.SetFeature(prefix+f.name, feature)
+	}
+	return nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.String, reflect.Map:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	}
+	return false
+}
+
+// valueKind returns feature kind for values of type t and whether the values are stored as a list
+func valueKind(t reflect.Type, kind string) (string, bool, error) {
+	var (
+		natural string
+		list    bool
+	)
+	if t.Implements(imageType) {
+		natural = FeatureKindBytes
+	} else {
+		elem := t
+		if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+			elem, list = t.Elem(), true
+		}
+		switch elem.Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			natural = FeatureKindInt64
+		case reflect.Float32, reflect.Float64:
+			natural = FeatureKindFloat
+		case reflect.String:
+			natural = FeatureKindBytes
+		case reflect.Slice:
+			if elem.Elem().Kind() == reflect.Uint8 {
+				natural = FeatureKindBytes
+			}
+		}
+	}
+	switch {
+	case natural == "":
+		return "", false, fmt.Errorf("unsupported type %s", t)
+	case kind == "" || kind == natural:
+		return natural, list, nil
+	case kind == FeatureKindFloat && natural == FeatureKindInt64:
+		return kind, list, nil
+	default:
+		return "", false, fmt.Errorf("type %s can't be stored as %s", t, kind)
+	}
+}
+
+func marshalValue(v reflect.Value, kind string) (*proto.Feature, error) {
+	kind, list, err := valueKind(v.Type(), kind)
+	if err != nil {
+		return nil, err
+	}
+	values := []reflect.Value{v}
+	if list {
+		values = make([]reflect.Value, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, v.Index(i))
+		}
+	}
+
+	switch kind {
+	case FeatureKindInt64:
+		ints := make([]int64, 0, len(values))
+		for _, v := range values {
+			i, err := intValue(v)
+			if err != nil {
+				return nil, err
+			}
+			ints = append(ints, i)
+		}
+		return &proto.Feature{Kind: &proto.Feature_Int64List{Int64List: &proto.Int64List{Value: ints}}}, nil
+	case FeatureKindFloat:
+		floats := make([]float32, 0, len(values))
+		for _, v := range values {
+			if v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64 {
+				floats = append(floats, float32(v.Float()))
+				continue
+			}
+			i, err := intValue(v)
+			if err != nil {
+				return nil, err
+			}
+			floats = append(floats, float32(i))
+		}
+		return &proto.Feature{Kind: &proto.Feature_FloatList{FloatList: &proto.FloatList{Value: floats}}}, nil
+	default:
+		bs := make([][]byte, 0, len(values))
+		for _, v := range values {
+			b, err := bytesValue(v)
+			if err != nil {
+				return nil, err
+			}
+			bs = append(bs, b)
+		}
+		return &proto.Feature{Kind: &proto.Feature_BytesList{BytesList: &proto.BytesList{Value: bs}}}, nil
+	}
+}
+
+func intValue(v reflect.Value) (int64, error) {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d overflows int64", v.Uint())
+		}
+		return int64(v.Uint()), nil
+	default:
+		return v.Int(), nil
+	}
+}
+
+func bytesValue(v reflect.Value) ([]byte, error) {
+	if v.Type().Implements(imageType) {
+		img, ok := v.Interface().(image.Image)
+		if !ok || img == nil || (v.Kind() == reflect.Ptr && v.IsNil()) {
+			return nil, fmt.Errorf("nil image")
+		}
+		buf := bytes.NewBuffer(nil)
+		if err := png.Encode(buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	if v.Kind() == reflect.String {
+		return []byte(v.String()), nil
+	}
+	return v.Bytes(), nil
+}
+
+func unmarshalStruct(ex *TFExample, rv reflect.Value, prefix string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		f, ok := parseExampleTag(sf)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+		if nestedStruct(sf.Type) {
+			nestedPrefix := fieldPrefix(sf, f, prefix)
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					if !ex.hasFeaturePrefix(nestedPrefix) || !fv.CanSet() {
+						continue
+					}
+					fv.Set(reflect.New(sf.Type.Elem()))
+				}
+				fv = fv.Elem()
+			}
+			if err := unmarshalStruct(ex, fv, nestedPrefix); err != nil {
+				return err
+			}
+			continue
+		}
+		if sf.PkgPath != "" || !ex.HasFeature(prefix+f.name) {
+			continue
+		}
+
+		if fv.Kind() == reflect.Ptr && !sf.Type.Implements(imageType) {
+			if fv.IsNil() {
+				fv.Set(reflect.New(sf.Type.Elem()))
+			}
+			fv = fv.Elem()
+		}
+		if err := unmarshalValue(ex, prefix+f.name, fv, f.kind); err != nil {
+			return fmt.Errorf("tfexample: field %s: %w", sf.Name, err)
+		}
+	}
+	return nil
+}
+
+func (e *TFExample) hasFeaturePrefix(prefix string) bool {
+	for name := range e.GetFeatures().GetFeature() {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func unmarshalValue(ex *TFExample, name string, v reflect.Value, kind string) error {
+	t := v.Type()
+	kind, list, err := valueKind(t, kind)
+	if err != nil {
+		return err
+	}
+
+	if t.Implements(imageType) {
+		img, err := ex.LookupImage(name)
+		if err != nil {
+			return err
+		}
+		if !reflect.TypeOf(img).AssignableTo(t) {
+			return fmt.Errorf("decoded image %T is not assignable to %s", img, t)
+		}
+		v.Set(reflect.ValueOf(img))
+		return nil
+	}
+
+	var values []reflect.Value
+	switch kind {
+	case FeatureKindInt64:
+		var ints []int64
+		if list {
+			ints, err = ex.LookupInt64List(name)
+		} else {
+			var i int64
+			i, err = ex.LookupInt64(name)
+			ints = []int64{i}
+		}
+		if err != nil {
+			return err
+		}
+		for _, i := range ints {
+			values = append(values, reflect.ValueOf(i))
+		}
+	case FeatureKindFloat:
+		var floats []float32
+		if list {
+			floats, err = ex.LookupFloatList(name)
+		} else {
+			var f float32
+			f, err = ex.LookupFloat(name)
+			floats = []float32{f}
+		}
+		if err != nil {
+			return err
+		}
+		for _, f := range floats {
+			values = append(values, reflect.ValueOf(f))
+		}
+	default:
+		var bs [][]byte
+		if list {
+			bs, err = ex.LookupBytesList(name)
+		} else {
+			var b []byte
+			b, err = ex.LookupBytes(name)
+			bs = [][]byte{b}
+		}
+		if err != nil {
+			return err
+		}
+		for _, b := range bs {
+			values = append(values, reflect.ValueOf(b))
+		}
+	}
+
+	if !list {
+		return setValue(v, values[0])
+	}
+	slice := reflect.MakeSlice(t, len(values), len(values))
+	for i, value := range values {
+		if err := setValue(slice.Index(i), value); err != nil {
+			return err
+		}
+	}
+	v.Set(slice)
+	return nil
+}
+
+// setValue sets dst to value, which is int64, float32 or []byte, checking for overflows
+func setValue(dst, value reflect.Value) error {
+	switch dst.Kind() {
+	case reflect.Bool:
+		i, err := integerValue(value)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(i != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := integerValue(value)
+		if err != nil {
+			return err
+		}
+		if dst.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, dst.Type())
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := integerValue(value)
+		if err != nil {
+			return err
+		}
+		if i < 0 || dst.OverflowUint(uint64(i)) {
+			return fmt.Errorf("value %d overflows %s", i, dst.Type())
+		}
+		dst.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(value.Float())
+	case reflect.String:
+		dst.SetString(string(value.Bytes()))
+	default: // []byte
+		dst.SetBytes(value.Bytes())
+	}
+	return nil
+}
+
+// integerValue returns value, which is int64 or float32, as int64. Floats have to be integral.
+func integerValue(value reflect.Value) (int64, error) {
+	if value.Kind() == reflect.Int64 {
+		return value.Int(), nil
+	}
+	f := value.Float()
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("value %v is not an integer", f)
+	}
+	return int64(f), nil
+}
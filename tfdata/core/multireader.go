@@ -0,0 +1,239 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/cmn"
+)
+
+type (
+	// TFRecordSource opens a single TFRecord file. TFRecordMultiReader opens sources lazily,
+	// when it starts reading from them, and closes them as soon as they are exhausted.
+	TFRecordSource func() (io.ReadCloser, error)
+
+	// InterleaveOptions defines the order in which TFRecordMultiReader reads records from multiple
+	// sources. It mirrors arguments of tf.data.Dataset.interleave.
+	InterleaveOptions struct {
+		// CycleLength is number of sources read at the same time. Default is 1, which means that
+		// sources are read one after another.
+		CycleLength int
+		// BlockLength is number of consecutive records read from a source before moving to the next one
+		// in the cycle. Default is 1.
+		BlockLength int
+		// Parallel makes each of CycleLength open sources read and decoded in a separate goroutine.
+		// The order of records is the same as without Parallel.
+		Parallel bool
+		// TFRecordOptions are used to create TFRecordReader for each of the sources.
+		TFRecordOptions TFRecordOptions
+	}
+
+	// TFRecordMultiReader reads TFExamples from multiple TFRecord sources as from a single TFExampleReader.
+	// TFRecordMultiReader is async-safe.
+	TFRecordMultiReader struct {
+		mtx     sync.Mutex
+		opts    InterleaveOptions
+		sources []TFRecordSource
+		next    int // index of the next source to open
+
+		cycle              []*interleaveElement
+		cycleIdx, blockIdx int
+		numOpen            int
+		closed             bool
+	}
+
+	// interleaveElement is an open source in the cycle
+	interleaveElement struct {
+		rc io.ReadCloser
+		r  *TFRecordReader
+
+		// used only in parallel mode
+		ch     chan *exampleResult
+		stop   chan struct{} // closed to stop prefetch goroutine
+		exited chan struct{} // closed by prefetch goroutine when it returns
+		err    error         // the last result of prefetch, returned by all subsequent reads
+	}
+
+	exampleResult struct {
+		ex  *TFExample
+		err error
+	}
+)
+
+var _ TFExampleReader = &TFRecordMultiReader{}
+
+// FileSource returns TFRecordSource which opens file at path.
+func FileSource(path string) TFRecordSource {
+	return func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+}
+
+// FileSources returns TFRecordSource for each of paths.
+func FileSources(paths ...string) []TFRecordSource {
+	sources := make([]TFRecordSource, 0, len(paths))
+	for _, path := range paths {
+		sources = append(sources, FileSource(path))
+	}
+	return sources
+}
+
+// GlobSources returns TFRecordSource for each of files matching pattern, in lexical order.
+func GlobSources(pattern string) ([]TFRecordSource, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return FileSources(paths...), nil
+}
+
+// NewTFRecordConcatReader creates TFRecordMultiReader which reads sources one after another.
+func NewTFRecordConcatReader(sources []TFRecordSource, opts ...TFRecordOptions) *TFRecordMultiReader {
+	return NewTFRecordInterleaveReader(sources, InterleaveOptions{TFRecordOptions: optionsFrom(opts)})
+}
+
+// NewTFRecordInterleaveReader creates TFRecordMultiReader which reads sources in the same order as
+// tf.data.Dataset.interleave: CycleLength sources are open at once and BlockLength records are read
+// from each of them in round-robin fashion. When a source is exhausted, next source takes its place in the cycle.
+func NewTFRecordInterleaveReader(sources []TFRecordSource, opts InterleaveOptions) *TFRecordMultiReader {
+	cmn.Assert(opts.CycleLength >= 0 && opts.BlockLength >= 0)
+	if opts.CycleLength == 0 {
+		opts.CycleLength = 1
+	}
+	if opts.BlockLength == 0 {
+		opts.BlockLength = 1
+	}
+	return &TFRecordMultiReader{
+		opts:    opts,
+		sources: sources,
+		cycle:   make([]*interleaveElement, opts.CycleLength),
+	}
+}
+
+// Read returns next TFExample in the interleave order. Returns io.EOF when all sources are exhausted.
+func (r *TFRecordMultiReader) Read() (*TFExample, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	cmn.AssertMsg(!r.closed, "read from closed TFRecordMultiReader")
+
+	for r.next < len(r.sources) || r.numOpen > 0 {
+		el := r.cycle[r.cycleIdx]
+		switch {
+		case el != nil:
+			ex, err := el.read()
+			if err == nil {
+				r.blockIdx++
+				if r.blockIdx == r.opts.BlockLength {
+					r.advance()
+				}
+				return ex, nil
+			}
+			if err != io.EOF {
+				return nil, err
+			}
+			if err := r.closeElement(r.cycleIdx); err != nil {
+				return nil, err
+			}
+			r.advance()
+		case r.next < len(r.sources):
+			if err := r.openElement(r.cycleIdx); err != nil {
+				return nil, err
+			}
+		default:
+			r.advance()
+		}
+	}
+	return nil, io.EOF
+}
+
+// Close closes all open sources and stops reading goroutines. TFRecordMultiReader
+// can't be used after Close.
+func (r *TFRecordMultiReader) Close() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	var err error
+	for i := range r.cycle {
+		if closeErr := r.closeElement(i); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (r *TFRecordMultiReader) advance() {
+	r.blockIdx = 0
+	r.cycleIdx = (r.cycleIdx + 1) % len(r.cycle)
+}
+
+func (r *TFRecordMultiReader) openElement(idx int) error {
+	// the source is skipped only when it's open, so Read can be retried after an error
+	rc, err := r.sources[r.next]()
+	if err != nil {
+		return err
+	}
+	r.next++
+
+	el := &interleaveElement{rc: rc, r: NewTFRecordReader(rc, r.opts.TFRecordOptions)}
+	if r.opts.Parallel {
+		el.ch = make(chan *exampleResult, r.opts.BlockLength)
+		el.stop, el.exited = make(chan struct{}), make(chan struct{})
+		go el.prefetch()
+	}
+	r.cycle[idx] = el
+	r.numOpen++
+	return nil
+}
+
+func (r *TFRecordMultiReader) closeElement(idx int) error {
+	el := r.cycle[idx]
+	if el == nil {
+		return nil
+	}
+	r.cycle[idx] = nil
+	r.numOpen--
+	if el.ch != nil {
+		// prefetch goroutine might be reading from el.rc, wait until it's done
+		close(el.stop)
+		<-el.exited
+	}
+	el.r.Close()
+	return el.rc.Close()
+}
+
+func (el *interleaveElement) read() (*TFExample, error) {
+	if el.ch == nil {
+		return el.r.Read()
+	}
+	if el.err != nil {
+		// prefetch has already returned, nothing more will be sent to el.ch
+		return nil, el.err
+	}
+	res := <-el.ch
+	el.err = res.err
+	return res.ex, res.err
+}
+
+// prefetch reads TFExamples until the first error (including io.EOF) and sends them to el.ch
+func (el *interleaveElement) prefetch() {
+	defer close(el.exited)
+	for {
+		ex, err := el.r.Read()
+		select {
+		case el.ch <- &exampleResult{ex: ex, err: err}:
+		case <-el.stop:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
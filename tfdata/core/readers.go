@@ -2,7 +2,10 @@
 
 package core
 
-import "io"
+import (
+	"context"
+	"io"
+)
 
 type (
 	// TFExampleReader returns io.EOF if there's nothing left to be read
@@ -48,6 +51,9 @@ type (
 var (
 	_ TFExampleReadWriter = &TFExampleChannel{}
 	_ SampleReadWriter    = &SampleChannel{}
+
+	_ TFExampleReaderContext = &TFExampleChannel{}
+	_ SampleReaderContext    = &SampleChannel{}
 )
 
 // TFExampleReaders / TFExampleWriters
@@ -64,6 +70,19 @@ func (c *TFExampleChannel) Read() (*TFExample, error) {
 	return ex, nil
 }
 
+// ReadContext behaves the same as Read, but returns ctx.Err() if ctx is done before TFExample is available
+func (c *TFExampleChannel) ReadContext(ctx context.Context) (*TFExample, error) {
+	select {
+	case ex, ok := <-c.ch:
+		if !ok {
+			return ex, io.EOF
+		}
+		return ex, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 func (c *TFExampleChannel) Write(example *TFExample) error {
 	c.ch <- example
 	return nil
@@ -87,6 +106,19 @@ func (c *SampleChannel) Read() (Sample, error) {
 	return sample, nil
 }
 
+// ReadContext behaves the same as Read, but returns ctx.Err() if ctx is done before Sample is available
+func (c *SampleChannel) ReadContext(ctx context.Context) (Sample, error) {
+	select {
+	case sample, ok := <-c.ch:
+		if !ok {
+			return sample, io.EOF
+		}
+		return sample, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 func (c *SampleChannel) Write(sample Sample) error {
 	c.ch <- sample
 	return nil
@@ -0,0 +1,129 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// CorruptionPolicy defines how TFRecordReader handles corrupted records
+type CorruptionPolicy int
+
+const (
+	// CorruptionFail - reading terminates with an error on the first corrupted record. It is the default policy.
+	CorruptionFail CorruptionPolicy = iota
+	// CorruptionSkip - corrupted regions of TFRecord are reported to TFRecordOptions.OnCorruption and skipped.
+	// Reading continues from the next valid record: if the length header of a record is corrupted,
+	// TFRecordReader looks for the next valid length header byte-by-byte.
+	CorruptionSkip
+)
+
+// resyncChunkSize is number of bytes read at once when looking for valid length header
+const resyncChunkSize = 32 * 1024
+
+type (
+	// TFRecordCorruption describes corrupted region of TFRecord, skipped by TFRecordReader.
+	TFRecordCorruption struct {
+		// Offset of the corrupted region in TFRecord. If TFRecord is compressed, it's offset in decompressed stream.
+		Offset int64
+		// Length of the corrupted region in bytes
+		Length int64
+		// Index of the first corrupted record, equal to number of records preceding it in TFRecord,
+		// including corrupted ones which have a valid length header.
+		Index int64
+		// Err is the reason of the corruption, for example HeaderChecksumError or PayloadChecksumError
+		Err error
+	}
+
+	// frameFault describes which part of TFRecord record is corrupted
+	frameFault int
+
+	// recordStream is io.Reader which allows to read again bytes already read from r
+	recordStream struct {
+		r       io.Reader
+		pending []byte
+	}
+)
+
+const (
+	noFault frameFault = iota
+	headerFault
+	payloadFault
+	truncatedFault
+)
+
+func (r *TFRecordReader) reportCorruption(corruption TFRecordCorruption) {
+	if r.opts.OnCorruption != nil {
+		r.opts.OnCorruption(corruption)
+	}
+}
+
+// skipCorruption reports corruption of TFRecord framing found by readRecord. If TFRecordReader
+// reads records for DecodeWorkers, corruption is only collected and reported by readDecoded.
+func (r *TFRecordReader) skipCorruption(corruption TFRecordCorruption) {
+	if r.opts.DecodeWorkers > 0 {
+		r.skipped = append(r.skipped, corruption)
+		return
+	}
+	r.reportCorruption(corruption)
+}
+
+// resync looks for the next valid length header, starting from the second byte of buf, where buf contains
+// bytes already read from the stream. When a valid length header is found, the stream is rewound to the header.
+// Returns number of skipped bytes. If the stream ends before a valid length header is found, the last bytes
+// which might be a beginning of a truncated header are left in the stream, so they are read and reported as
+// TruncatedRecordError. Returns io.EOF if there are no such bytes.
+func (r *TFRecordReader) resync(buf []byte) (int64, error) {
+	var (
+		skipped int64
+		from    = 1
+		data    = append(make([]byte, 0, len(buf)+resyncChunkSize), buf...)
+	)
+
+	for {
+		for i := from; i+headerSize <= len(data); i++ {
+			if r.c.Verify(data[i:i+8], binary.LittleEndian.Uint32(data[i+8:i+headerSize])) == nil {
+				r.stream.unread(data[i:])
+				return skipped + int64(i), nil
+			}
+		}
+
+		// keep bytes which might be a beginning of a valid header and read more
+		keep := len(data) - headerSize + 1
+		if keep < from {
+			keep = from
+		}
+		skipped += int64(keep)
+		data = append(data[:0], data[keep:]...)
+		from = 0
+
+		chunk := data[len(data):cap(data)]
+		n, err := io.ReadFull(r.stream, chunk)
+		data = data[:len(data)+n]
+		if n == 0 {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				if len(data) > 0 {
+					r.stream.unread(data)
+					return skipped, nil
+				}
+				return skipped, io.EOF
+			}
+			return skipped, err
+		}
+	}
+}
+
+func (s *recordStream) Read(p []byte) (int, error) {
+	if len(s.pending) > 0 {
+		n := copy(p, s.pending)
+		s.pending = s.pending[n:]
+		return n, nil
+	}
+	return s.r.Read(p)
+}
+
+// unread makes p to be read again before the rest of the stream. It's expected that s.pending is empty.
+func (s *recordStream) unread(p []byte) {
+	s.pending = append(append(make([]byte, 0, len(p)+len(s.pending)), p...), s.pending...)
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import (
+	"context"
+	"io"
+
+	"github.com/NVIDIA/go-tfdata/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+type (
+	// TFSequenceExample is a wrapper over proto.SequenceExample struct generated by protoc from TensorFlow
+	// tf.SequenceExample proto files. It consists of context - features describing the whole sequence,
+	// and feature lists - named lists of features, where each of the features describes a single step of the sequence.
+	TFSequenceExample struct {
+		proto.SequenceExample
+	}
+
+	// TFSequenceExampleReader returns io.EOF if there's nothing left to be read
+	TFSequenceExampleReader interface {
+		Read() (ex *TFSequenceExample, err error)
+	}
+
+	TFSequenceExampleWriter interface {
+		Write(ex *TFSequenceExample) error
+		Close()
+	}
+
+	TFSequenceExampleReadWriter interface {
+		TFSequenceExampleReader
+		TFSequenceExampleWriter
+	}
+
+	// TFSequenceExampleChannel is simple implementation of TFSequenceExampleReadWriter
+	TFSequenceExampleChannel struct {
+		ch chan *TFSequenceExample
+	}
+
+	// tfRecordSequenceReader reads TFSequenceExamples from TFRecordReader
+	tfRecordSequenceReader struct {
+		r *TFRecordReader
+	}
+)
+
+var (
+	_ TFSequenceExampleReadWriter = &TFSequenceExampleChannel{}
+	_ TFSequenceExampleReader     = &tfRecordSequenceReader{}
+
+	_ TFSequenceExampleReaderContext = &TFSequenceExampleChannel{}
+)
+
+// NewTFSequenceExample initializes empty TFSequenceExample and returns it.
+func NewTFSequenceExample() *TFSequenceExample {
+	return &TFSequenceExample{proto.SequenceExample{
+		Context:      &proto.Features{Feature: make(map[string]*proto.Feature)},
+		FeatureLists: &proto.FeatureLists{FeatureList: make(map[string]*proto.FeatureList)},
+	}}
+}
+
+// ContextExample returns TFExample sharing features with the context of e. All of TFExample
+// accessors can be used to read and modify the context, for example e.ContextExample().AddInt64("length", 3).
+func (e *TFSequenceExample) ContextExample() *TFExample {
+	if e.Context == nil {
+		e.Context = &proto.Features{Feature: make(map[string]*proto.Feature)}
+	}
+	return &TFExample{proto.Example{Features: e.Context}}
+}
+
+func (e *TFSequenceExample) HasFeatureList(name string) bool {
+	_, ok := e.GetFeatureLists().GetFeatureList()[name]
+	return ok
+}
+
+func (e *TFSequenceExample) GetFeatureList(name string) *proto.FeatureList {
+	return e.GetFeatureLists().GetFeatureList()[name]
+}
+
+func (e *TFSequenceExample) SetFeatureList(name string, list *proto.FeatureList) {
+	if e.FeatureLists == nil {
+		e.FeatureLists = &proto.FeatureLists{FeatureList: make(map[string]*proto.FeatureList)}
+	}
+	e.FeatureLists.FeatureList[name] = list
+}
+
+// AddFeatureListStep appends feature as the last step of feature list name
+func (e *TFSequenceExample) AddFeatureListStep(name string, feature *proto.Feature) {
+	list := e.GetFeatureList(name)
+	if list == nil {
+		list = &proto.FeatureList{}
+		e.SetFeatureList(name, list)
+	}
+	list.Feature = append(list.Feature, feature)
+}
+
+// AddInt64FeatureList sets feature list name, where i-th step is steps[i] Int64List
+func (e *TFSequenceExample) AddInt64FeatureList(name string, steps [][]int64) {
+	list := &proto.FeatureList{Feature: make([]*proto.Feature, 0, len(steps))}
+	for _, step := range steps {
+		list.Feature = append(list.Feature, &proto.Feature{Kind: &proto.Feature_Int64List{Int64List: &proto.Int64List{Value: step}}})
+	}
+	e.SetFeatureList(name, list)
+}
+
+func (e *TFSequenceExample) GetInt64FeatureList(name string) [][]int64 {
+	features := e.GetFeatureList(name).GetFeature()
+	steps := make([][]int64, 0, len(features))
+	for _, f := range features {
+		steps = append(steps, f.GetInt64List().GetValue())
+	}
+	return steps
+}
+
+// AddFloatFeatureList sets feature list name, where i-th step is steps[i] FloatList
+func (e *TFSequenceExample) AddFloatFeatureList(name string, steps [][]float32) {
+	list := &proto.FeatureList{Feature: make([]*proto.Feature, 0, len(steps))}
+	for _, step := range steps {
+		list.Feature = append(list.Feature, &proto.Feature{Kind: &proto.Feature_FloatList{FloatList: &proto.FloatList{Value: step}}})
+	}
+	e.SetFeatureList(name, list)
+}
+
+func (e *TFSequenceExample) GetFloatFeatureList(name string) [][]float32 {
+	features := e.GetFeatureList(name).GetFeature()
+	steps := make([][]float32, 0, len(features))
+	for _, f := range features {
+		steps = append(steps, f.GetFloatList().GetValue())
+	}
+	return steps
+}
+
+// AddBytesFeatureList sets feature list name, where i-th step is steps[i] BytesList,
+// for example i-th frame of a video.
+func (e *TFSequenceExample) AddBytesFeatureList(name string, steps [][][]byte) {
+	list := &proto.FeatureList{Feature: make([]*proto.Feature, 0, len(steps))}
+	for _, step := range steps {
+		list.Feature = append(list.Feature, &proto.Feature{Kind: &proto.Feature_BytesList{BytesList: &proto.BytesList{Value: step}}})
+	}
+	e.SetFeatureList(name, list)
+}
+
+// GetBytesFeatureList returns values of feature list name: i-th element contains all values of i-th step.
+func (e *TFSequenceExample) GetBytesFeatureList(name string) [][][]byte {
+	features := e.GetFeatureList(name).GetFeature()
+	steps := make([][][]byte, 0, len(features))
+	for _, f := range features {
+		steps = append(steps, f.GetBytesList().GetValue())
+	}
+	return steps
+}
+
+// TFRecordReader / TFRecordWriter helpers
+
+// ReadSequenceExample reads next TFSequenceExample from TFRecord.
+func (r *TFRecordReader) ReadSequenceExample() (*TFSequenceExample, error) {
+	ex := &TFSequenceExample{}
+	return ex, r.ReadNext(ex)
+}
+
+// SequenceExamples returns TFSequenceExampleReader reading TFSequenceExamples from r.
+func (r *TFRecordReader) SequenceExamples() TFSequenceExampleReader {
+	return &tfRecordSequenceReader{r: r}
+}
+
+func (r *tfRecordSequenceReader) Read() (*TFSequenceExample, error) {
+	return r.r.ReadSequenceExample()
+}
+
+func (w *TFRecordWriter) WriteSequenceExample(ex *TFSequenceExample) (n int, err error) {
+	return w.WriteMessage(ex)
+}
+
+// WriteSequenceMessages reads and writes TFSequenceExamples from reader until io.EOF.
+func (w *TFRecordWriter) WriteSequenceMessages(reader TFSequenceExampleReader) error {
+	return writeMessages(sequenceExamplesSource(reader), w.writeMessage)
+}
+
+// WriteSequenceMessagesAsync behaves the same as WriteMessagesAsync but operates on TFSequenceExamples.
+func (w *TFRecordWriter) WriteSequenceMessagesAsync(reader TFSequenceExampleReader, numWorkers int) error {
+	return w.WriteSequenceMessagesAsyncContext(context.Background(), reader, numWorkers)
+}
+
+// WriteSequenceMessagesAsyncContext behaves the same as WriteMessagesAsyncContext but operates on TFSequenceExamples.
+func (w *TFRecordWriter) WriteSequenceMessagesAsyncContext(ctx context.Context, reader TFSequenceExampleReader, numWorkers int) error {
+	return writeMessagesAsync(ctx, sequenceExamplesSourceContext(reader), numWorkers, w.writeMessage)
+}
+
+// WriteSequenceMessagesOrdered behaves the same as WriteMessagesOrdered but operates on TFSequenceExamples.
+func (w *TFRecordWriter) WriteSequenceMessagesOrdered(reader TFSequenceExampleReader, numWorkers int) error {
+	return writeMessagesOrdered(sequenceExamplesSource(reader), numWorkers, w.Write)
+}
+
+// WriteSequenceMessages reads and writes TFSequenceExamples from reader until io.EOF.
+func (w *TFRecordShardWriter) WriteSequenceMessages(reader TFSequenceExampleReader) error {
+	return writeMessages(sequenceExamplesSource(reader), w.writeMessage)
+}
+
+// WriteSequenceMessagesAsync behaves the same as WriteMessagesAsync but operates on TFSequenceExamples.
+func (w *TFRecordShardWriter) WriteSequenceMessagesAsync(reader TFSequenceExampleReader, numWorkers int) error {
+	return w.WriteSequenceMessagesAsyncContext(context.Background(), reader, numWorkers)
+}
+
+// WriteSequenceMessagesAsyncContext behaves the same as WriteMessagesAsyncContext but operates on TFSequenceExamples.
+func (w *TFRecordShardWriter) WriteSequenceMessagesAsyncContext(ctx context.Context, reader TFSequenceExampleReader, numWorkers int) error {
+	return writeMessagesAsync(ctx, sequenceExamplesSourceContext(reader), numWorkers, w.writeMessage)
+}
+
+// WriteSequenceMessagesOrdered behaves the same as WriteMessagesOrdered but operates on TFSequenceExamples.
+func (w *TFRecordShardWriter) WriteSequenceMessagesOrdered(reader TFSequenceExampleReader, numWorkers int) error {
+	return writeMessagesOrdered(sequenceExamplesSource(reader), numWorkers, w.Write)
+}
+
+func sequenceExamplesSource(reader TFSequenceExampleReader) func() (protobuf.Message, error) {
+	return func() (protobuf.Message, error) {
+		ex, err := reader.Read()
+		return ex, err
+	}
+}
+
+func sequenceExamplesSourceContext(reader TFSequenceExampleReader) func(context.Context) (protobuf.Message, error) {
+	rc := NewTFSequenceExampleReaderContext(reader)
+	return func(ctx context.Context) (protobuf.Message, error) {
+		ex, err := rc.ReadContext(ctx)
+		return ex, err
+	}
+}
+
+// TFSequenceExampleChannel
+
+func NewTFSequenceExampleChannel(bufSize int) *TFSequenceExampleChannel {
+	return &TFSequenceExampleChannel{ch: make(chan *TFSequenceExample, bufSize)}
+}
+
+func (c *TFSequenceExampleChannel) Read() (*TFSequenceExample, error) {
+	ex, ok := <-c.ch
+	if !ok {
+		return ex, io.EOF
+	}
+	return ex, nil
+}
+
+// ReadContext behaves the same as Read, but returns ctx.Err() if ctx is done before TFSequenceExample is available
+func (c *TFSequenceExampleChannel) ReadContext(ctx context.Context) (*TFSequenceExample, error) {
+	select {
+	case ex, ok := <-c.ch:
+		if !ok {
+			return ex, io.EOF
+		}
+		return ex, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *TFSequenceExampleChannel) Write(example *TFSequenceExample) error {
+	c.ch <- example
+	return nil
+}
+
+func (c *TFSequenceExampleChannel) Close() {
+	close(c.ch)
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/shard"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+type (
+	// TFRecordShard describes a single TFRecord file written by TFRecordShardWriter
+	TFRecordShard struct {
+		Name    string `json:"name"`
+		Records int    `json:"records"`
+		Bytes   int64  `json:"bytes"` // number of bytes written to the file, after compression
+	}
+
+	// TFRecordShardOptions defines how TFRecordShardWriter splits TFRecord into shards
+	TFRecordShardOptions struct {
+		// Pattern is a format (as in fmt package) of shard names with two integer verbs: index of a shard and
+		// total number of shards, for example "train-%05d-of-%05d.tfrecord". Total number of shards is known
+		// only after all of them are written, so before Close shards are named with total equal to 0
+		// and renamed on Close.
+		Pattern string
+		// MaxBytes is maximum number of bytes of TFRecord records in a shard. Shard can exceed MaxBytes only
+		// if it has a single record. Size is counted before compression. 0 means no limit.
+		MaxBytes int64
+		// MaxExamples is maximum number of records in a shard. 0 means no limit.
+		MaxExamples int
+		// TFRecordOptions are used to create TFRecordWriter for each of the shards. Index is not supported.
+		TFRecordOptions TFRecordOptions
+
+		// Create creates a new shard with given name. If not provided, os.Create is used.
+		Create func(name string) (io.WriteCloser, error)
+		// Rename renames already written shard. If not provided, os.Rename is used.
+		Rename func(oldName, newName string) error
+	}
+
+	// TFRecordShardWriter writes TFRecord records into multiple files, rotating them according to
+	// TFRecordShardOptions. Shards names, records counts and sizes are available via Shards.
+	TFRecordShardWriter struct {
+		opts   TFRecordShardOptions
+		shards *shard.Writer
+		w      *TFRecordWriter // writes the current shard, nil if there's none
+	}
+)
+
+// NewTFRecordShardWriter creates TFRecordShardWriter. Shards are created lazily, when the first record
+// of a shard is written, so no files are created if nothing is written. Returns error if opts.Pattern
+// doesn't have two integer verbs, as described in TFRecordShardOptions, or if TFRecord index is requested.
+func NewTFRecordShardWriter(opts TFRecordShardOptions) (*TFRecordShardWriter, error) {
+	if opts.TFRecordOptions.Index != nil {
+		return nil, errors.New("TFRecord index is not supported by TFRecordShardWriter")
+	}
+	shards, err := shard.NewWriter(shard.Options{
+		Pattern:  opts.Pattern,
+		MaxBytes: opts.MaxBytes,
+		MaxCount: opts.MaxExamples,
+		Create:   opts.Create,
+		Rename:   opts.Rename,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TFRecordShardWriter{opts: opts, shards: shards}, nil
+}
+
+// Write writes p as a single TFRecord record into the current shard, rotating it before if needed.
+func (w *TFRecordShardWriter) Write(p []byte) (n int, err error) {
+	if w.shards.ShouldRotate(int64(headerSize + len(p) + footerSize)) {
+		if err := w.closeShard(); err != nil {
+			return 0, err
+		}
+	}
+	if w.w == nil {
+		dst, err := w.shards.Open()
+		if err != nil {
+			return 0, err
+		}
+		w.w = NewTFRecordWriter(dst, w.opts.TFRecordOptions)
+	}
+
+	n, err = w.w.Write(p)
+	if err == nil {
+		w.shards.Add(int64(n))
+	}
+	return n, err
+}
+
+// WriteRecord writes p as a raw payload of a single record. It's the same as Write.
+func (w *TFRecordShardWriter) WriteRecord(p []byte) (n int, err error) {
+	return w.Write(p)
+}
+
+// WriteRecords reads raw records from reader until io.EOF and writes them to shards byte-for-byte,
+// without decoding. It can be used to re-shard TFRecord files.
+func (w *TFRecordShardWriter) WriteRecords(reader TFRecordRawReader) error {
+	return writeRecords(reader, w.Write)
+}
+
+func (w *TFRecordShardWriter) WriteExample(example *TFExample) (n int, err error) {
+	return w.WriteMessage(example)
+}
+
+// WriteMessage marshals message and writes it to the current shard
+func (w *TFRecordShardWriter) WriteMessage(message protobuf.Message) (n int, err error) {
+	p, err := marshalOptions.Marshal(message)
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(p)
+}
+
+// WriteMessages reads and writes TFExamples from reader until io.EOF.
+func (w *TFRecordShardWriter) WriteMessages(reader TFExampleReader) error {
+	return writeMessages(examplesSource(reader), w.writeMessage)
+}
+
+// WriteMessagesAsync reads TFExamples from reader asynchronously and writes them synchronously to shards.
+// See TFRecordWriter.WriteMessagesAsync.
+func (w *TFRecordShardWriter) WriteMessagesAsync(reader TFExampleReader, numWorkers int) error {
+	return w.WriteMessagesAsyncContext(context.Background(), reader, numWorkers)
+}
+
+// WriteMessagesAsyncContext behaves the same as WriteMessagesAsync, but stops reading when ctx is done.
+// See TFRecordWriter.WriteMessagesAsyncContext.
+func (w *TFRecordShardWriter) WriteMessagesAsyncContext(ctx context.Context, reader TFExampleReader, numWorkers int) error {
+	return writeMessagesAsync(ctx, examplesSourceContext(reader), numWorkers, w.writeMessage)
+}
+
+// WriteMessagesOrdered reads TFExamples from reader sequentially, marshals them asynchronously and writes them
+// to shards in the same order as they were read. See TFRecordWriter.WriteMessagesOrdered.
+func (w *TFRecordShardWriter) WriteMessagesOrdered(reader TFExampleReader, numWorkers int) error {
+	return writeMessagesOrdered(examplesSource(reader), numWorkers, w.Write)
+}
+
+func (w *TFRecordShardWriter) writeMessage(message protobuf.Message) error {
+	_, err := w.WriteMessage(message)
+	return err
+}
+
+// Close closes the last shard and renames all of the shards to their final names.
+func (w *TFRecordShardWriter) Close() error {
+	if err := w.closeShard(); err != nil {
+		return err
+	}
+	return w.shards.Close()
+}
+
+// Shards returns manifest of shards written so far. Names are final only after Close.
+func (w *TFRecordShardWriter) Shards() []TFRecordShard {
+	infos := w.shards.Shards()
+	shards := make([]TFRecordShard, 0, len(infos))
+	for _, info := range infos {
+		shards = append(shards, TFRecordShard{Name: info.Name, Records: info.Count, Bytes: info.Bytes})
+	}
+	return shards
+}
+
+func (w *TFRecordShardWriter) closeShard() error {
+	if w.w == nil {
+		return nil
+	}
+	err := w.w.Close()
+	if closeErr := w.shards.CloseShard(); err == nil {
+		err = closeErr
+	}
+	w.w = nil
+	return err
+}
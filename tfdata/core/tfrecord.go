@@ -3,6 +3,8 @@
 package core
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/binary"
 	"io"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/NVIDIA/go-tfdata/tfdata/internal/checksum"
 	"github.com/NVIDIA/go-tfdata/tfdata/internal/cmn"
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/ordered"
 	protobuf "google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
@@ -36,25 +39,123 @@ type (
 		ReadExamples(writer TFExampleWriter) error
 	}
 
+	// TFRecordRawReader reads raw payloads of TFRecord records, without decoding them.
+	// ReadRecord returns io.EOF if there's nothing left to be read
+	TFRecordRawReader interface {
+		ReadRecord() ([]byte, error)
+	}
+
+	// TFRecordOptions defines how TFRecordReader and TFRecordWriter process TFRecord files.
+	// Zero value of TFRecordOptions describes uncompressed TFRecord.
+	TFRecordOptions struct {
+		// Compression of TFRecord. AutoCompression can be used only with TFRecordReader.
+		Compression CompressionType
+		// Index, if set, makes TFRecordWriter write TFRecord index along with TFRecord,
+		// see TFRecordIndex. Can be used only with uncompressed TFRecord.
+		Index io.Writer
+		// Corruption defines how TFRecordReader handles corrupted records. By default, reading fails
+		// on the first corrupted record.
+		Corruption CorruptionPolicy
+		// OnCorruption, if set, is called by TFRecordReader for each corrupted region skipped
+		// with CorruptionSkip policy.
+		OnCorruption func(TFRecordCorruption)
+		// MaxRecordSize is maximum length of record's payload accepted by TFRecordReader. Records exceeding
+		// it are reported with OversizedRecordError. If 0, DefaultMaxRecordSize is used.
+		MaxRecordSize int64
+		// Checksum defines which checksums are verified by TFRecordReader. TFRecordWriter always writes
+		// all of checksums. By default, all of checksums are verified.
+		Checksum ChecksumPolicy
+		// Checksummer, if set, is used instead of the default CRC32C implementation to compute and verify checksums.
+		Checksummer Checksummer
+		// DecodeWorkers, if greater than 0, makes TFRecordReader.Read unmarshal TFExamples with DecodeWorkers
+		// goroutines. Records are still read sequentially and TFExamples are returned in order of TFRecord.
+		// Such TFRecordReader has to be closed and can't be read with methods other than Read.
+		DecodeWorkers int
+		// BufferSize, if greater than 0, makes TFRecordReader and TFRecordWriter buffer reads from and writes to
+		// the underlying reader or writer. Buffered TFRecordWriter has to be closed to flush the buffer.
+		BufferSize int
+	}
+
 	// TFRecordWriter implements TFRecordWriter interface
 	// It writes objects into writer w with checksums provided by c
 	TFRecordWriter struct {
-		w io.Writer
-		c checksum.Checksummer
+		w        io.Writer
+		c        checksum.Checksummer
+		closer   io.Closer     // flushes compressor, nil if TFRecord is not compressed
+		buffered *bufio.Writer // nil if TFRecordWriter is not buffered
+		index    *TFRecordIndexWriter
+		offset   int64
+
+		buf []byte // reused for assembling records
 	}
 
-	// TFRecordReader implements TFRecordReader interface
+	// TFRecordReader implements TFRecordReaderInterface and TFRecordRawReader interfaces
 	// It reads objects from reader r and verify checksums with c
 	TFRecordReader struct {
-		r io.Reader
-		c checksum.Checksummer
+		r    io.Reader
+		c    checksum.Checksummer
+		opts TFRecordOptions
+
+		initialized bool
+		initErr     error
+
+		stream *recordStream
+		frame  frameReader
+		offset int64 // offset of the next record in (decompressed) TFRecord
+		index  int64 // index of the next record
+
+		decoderMtx sync.Mutex
+		decoder    *ordered.Processor // decodes TFExamples if DecodeWorkers is set
+		closed     bool
+		// skipped are corruptions found by readRecord since the last record read for DecodeWorkers.
+		// They are reported with the next TFExample, so all of corruptions are reported in order.
+		skipped []TFRecordCorruption
+		rawErr  error // error which ended reading records for DecodeWorkers, returned after skipped corruptions
 	}
 )
 
+const (
+	// headerSize is size of TFRecord length header: uint64(length) + uint32 cksm
+	headerSize = 12
+
+	// DefaultMaxRecordSize is default maximum length of record's payload accepted by TFRecordReader.
+	// It's equal to the maximum size of serialized protobuf message.
+	DefaultMaxRecordSize = 2 << 30
+
+	// streamingChunkSize - payloads longer than it are read in chunks, unless size of TFRecord is known,
+	// so memory is allocated only for bytes which are actually present in TFRecord.
+	streamingChunkSize = 1 << 20
+
+	// maxRetainedBufferSize - buffers larger than it are not reused by TFRecordReader and TFRecordWriter,
+	// so a single large record doesn't keep memory occupied.
+	maxRetainedBufferSize = 4 << 20
+)
+
 // NewTFRecordWriter creates and initializes TFRecordWriter with writer w and CRC checksumming method.
+// If opts are provided, TFRecordWriter writes TFRecord accordingly to them. If compression is enabled,
+// TFRecordWriter has to be closed to flush compressed data.
 // Returns pointer to created TFRecordWriter
-func NewTFRecordWriter(w io.Writer) *TFRecordWriter {
-	return &TFRecordWriter{w: w, c: checksum.NewCRCChecksummer()}
+func NewTFRecordWriter(w io.Writer, opts ...TFRecordOptions) *TFRecordWriter {
+	o := optionsFrom(opts)
+	writer := &TFRecordWriter{c: checksummerFrom(o), buf: make([]byte, 0, headerSize)}
+	writer.w, writer.closer = compressWriter(w, o.Compression)
+	if o.BufferSize > 0 {
+		writer.buffered = bufio.NewWriterSize(writer.w, o.BufferSize)
+		writer.w = writer.buffered
+	}
+	if o.Index != nil {
+		cmn.AssertMsg(o.Compression == NoCompression, "TFRecord index can't be written for compressed TFRecord")
+		writer.index = NewTFRecordIndexWriter(o.Index)
+	}
+	return writer
+}
+
+func optionsFrom(opts []TFRecordOptions) TFRecordOptions {
+	if len(opts) == 0 {
+		return TFRecordOptions{}
+	}
+	cmn.Assert(len(opts) == 1)
+	return opts[0]
 }
 
 // Write writes p into writer into format specified in https://www.tensorflow.org/tutorials/load_data/tfrecord#tfrecords_format_details.
@@ -62,56 +163,138 @@ func NewTFRecordWriter(w io.Writer) *TFRecordWriter {
 // Write is not atomic, meaning that underlying write error might leave internal writer in invalid TFRecord state
 // Returns total number of written bytes and error if occurred
 func (w *TFRecordWriter) Write(p []byte) (n int, err error) {
-	var (
-		total         = 0
-		lengthHeader  = make([]byte, 12) // uint64(length) + uint32 cksm
-		dataCksmBytes = make([]byte, 4)  // uint32 cksm
+	if len(p) <= maxRetainedBufferSize {
+		// assemble the whole record, so it's written with a single write
+		return w.writeRecord(append(w.buf[:headerSize], p...))
+	}
 
+	var (
+		total  = 0
+		header = w.buf[:headerSize]
+		footer [footerSize]byte
 	)
+	putHeader(header, len(p), w.c)
+	binary.LittleEndian.PutUint32(footer[:], w.c.Get(p))
 
-	binary.LittleEndian.PutUint64(lengthHeader[:8], uint64(len(p)))
-	binary.LittleEndian.PutUint32(lengthHeader[8:12], w.c.Get(lengthHeader[:8]))
-	binary.LittleEndian.PutUint32(dataCksmBytes, w.c.Get(p))
-
-	read, err := w.w.Write(lengthHeader)
-	total += read
+	written, err := w.w.Write(header)
+	total += written
 	if err == nil {
-		read, err = w.w.Write(p)
-		total += read
+		written, err = w.w.Write(p)
+		total += written
 	}
 	if err == nil {
-		read, err = w.w.Write(dataCksmBytes)
-		total += read
+		written, err = w.w.Write(footer[:])
+		total += written
 	}
+	return total, w.recordWritten(total, err)
+}
+
+// WriteRecord writes p as a raw payload of a single record. It's the same as Write.
+func (w *TFRecordWriter) WriteRecord(p []byte) (n int, err error) {
+	return w.Write(p)
+}
 
-	return total, err
+// WriteRecords reads raw records from reader until io.EOF and writes them byte-for-byte, without decoding.
+// If reader is TFRecordReader, its borrowed payloads are written, so records are not copied.
+func (w *TFRecordWriter) WriteRecords(reader TFRecordRawReader) error {
+	return writeRecords(reader, w.Write)
+}
+
+// writeRecords reads raw records from reader until io.EOF and passes them to write.
+func writeRecords(reader TFRecordRawReader, write func([]byte) (int, error)) error {
+	read := reader.ReadRecord
+	if r, ok := reader.(*TFRecordReader); ok {
+		read = r.ReadBorrowed
+	}
+	for {
+		p, err := read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := write(p); err != nil {
+			return err
+		}
+	}
 }
 
 func (w *TFRecordWriter) WriteExample(example *TFExample) (n int, err error) {
 	return w.WriteMessage(example)
 }
 
-// WriteMessage marshals message and writes it to TFRecord
+// marshalOptions makes TFRecordWriter produce byte-identical records for equal messages
+var marshalOptions = protobuf.MarshalOptions{Deterministic: true}
+
+// WriteMessage marshals message and writes it to TFRecord. Marshalling is deterministic: features of TFExample
+// are always written in the same order.
 func (w *TFRecordWriter) WriteMessage(message protoreflect.ProtoMessage) (n int, err error) {
-	p, err := protobuf.Marshal(message)
+	record, err := marshalOptions.MarshalAppend(w.buf[:headerSize], message)
 	if err != nil {
 		return 0, err
 	}
+	return w.writeRecord(record)
+}
 
-	return w.Write(p)
+// writeRecord writes record, which consists of space for length header followed by payload. Record is assembled
+// in w.buf, which is retained for the next writes if it isn't too large.
+func (w *TFRecordWriter) writeRecord(record []byte) (n int, err error) {
+	payloadLength := len(record) - headerSize
+	putHeader(record[:headerSize], payloadLength, w.c)
+	record = append(record, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(record[headerSize+payloadLength:], w.c.Get(record[headerSize:headerSize+payloadLength]))
+	if cap(record) <= maxRetainedBufferSize {
+		w.buf = record[:0]
+	}
+
+	n, err = w.w.Write(record)
+	return n, w.recordWritten(n, err)
+}
+
+// putHeader puts length header of a record with payload of given length into header
+func putHeader(header []byte, payloadLength int, c checksum.Checksummer) {
+	binary.LittleEndian.PutUint64(header[:8], uint64(payloadLength))
+	binary.LittleEndian.PutUint32(header[8:headerSize], c.Get(header[:8]))
+}
+
+// recordWritten updates TFRecord index after the record of given size was written
+func (w *TFRecordWriter) recordWritten(size int, err error) error {
+	if err == nil && w.index != nil {
+		err = w.index.Write(TFRecordIndexEntry{Offset: w.offset, Size: int64(size)})
+	}
+	w.offset += int64(size)
+	return err
 }
 
 // WriteMessages reads and writes to TFRecord messages one-by-one from ch and terminates
 // when ch is closed and empty. WriteMessages doesn't close ch itself.
 // Returns error immediately if occurred, without processing subsequent messages
 func (w *TFRecordWriter) WriteMessages(reader TFExampleReader) error {
+	return writeMessages(examplesSource(reader), w.writeMessage)
+}
+
+func (w *TFRecordWriter) writeMessage(message protobuf.Message) error {
+	_, err := w.WriteMessage(message)
+	return err
+}
+
+// examplesSource adapts reader to be used with writeMessages and writeMessagesAsync
+func examplesSource(reader TFExampleReader) func() (protobuf.Message, error) {
+	return func() (protobuf.Message, error) {
+		ex, err := reader.Read()
+		return ex, err
+	}
+}
+
+// writeMessages reads messages from read until io.EOF and passes them to write.
+func writeMessages(read func() (protobuf.Message, error), write func(protobuf.Message) error) error {
 	var (
-		ex  *TFExample
-		err error
+		message protobuf.Message
+		err     error
 	)
-	for ex, err = reader.Read(); err == nil; ex, err = reader.Read() {
-		_, err := w.WriteMessage(ex)
-		if err != nil {
+	for message, err = read(); err == nil; message, err = read() {
+		if err := write(message); err != nil {
 			return err
 		}
 	}
@@ -126,10 +309,32 @@ func (w *TFRecordWriter) WriteMessages(reader TFExampleReader) error {
 // All underlying readers will be called asynchronously. They all should be async-safe
 // Almost all of transformations
 func (w *TFRecordWriter) WriteMessagesAsync(reader TFExampleReader, numWorkers int) error {
+	return w.WriteMessagesAsyncContext(context.Background(), reader, numWorkers)
+}
+
+// WriteMessagesAsyncContext behaves the same as WriteMessagesAsync, but stops reading when ctx is done
+// and returns ctx.Err(). If reader implements TFExampleReaderContext, pending reads are canceled as well.
+func (w *TFRecordWriter) WriteMessagesAsyncContext(ctx context.Context, reader TFExampleReader, numWorkers int) error {
+	return writeMessagesAsync(ctx, examplesSourceContext(reader), numWorkers, w.writeMessage)
+}
+
+// examplesSourceContext adapts reader to be used with writeMessagesAsync
+func examplesSourceContext(reader TFExampleReader) func(context.Context) (protobuf.Message, error) {
+	rc := NewTFExampleReaderContext(reader)
+	return func(ctx context.Context) (protobuf.Message, error) {
+		ex, err := rc.ReadContext(ctx)
+		return ex, err
+	}
+}
+
+// writeMessagesAsync reads messages from read with numWorkers goroutines and passes them
+// synchronously to write. Reading stops when ctx is done.
+func writeMessagesAsync(parent context.Context, read func(context.Context) (protobuf.Message, error), numWorkers int,
+	write func(protobuf.Message) error) error {
 	cmn.Assert(numWorkers > 0)
-	ch := make(chan *TFExample)
+	ch := make(chan protobuf.Message)
 	errCh := make(chan error, numWorkers)
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 	wg := sync.WaitGroup{}
 	wg.Add(numWorkers)
@@ -138,7 +343,7 @@ func (w *TFRecordWriter) WriteMessagesAsync(reader TFExampleReader, numWorkers i
 		go func() {
 			defer wg.Done()
 			for {
-				ex, err := reader.Read()
+				message, err := read(ctx)
 				if err != nil {
 					if err != io.EOF {
 						errCh <- err
@@ -146,7 +351,7 @@ func (w *TFRecordWriter) WriteMessagesAsync(reader TFExampleReader, numWorkers i
 					return
 				}
 				select {
-				case ch <- ex:
+				case ch <- message:
 					break
 				case <-ctx.Done():
 					return
@@ -167,16 +372,74 @@ func (w *TFRecordWriter) WriteMessagesAsync(reader TFExampleReader, numWorkers i
 	// otherwise they would hang on the chanel forever. We have to use context:
 	// we can't close chanel in this loop as workers would panic if they were waiting
 	// on the chanel
-	for ex := range ch {
-		_, err := w.WriteMessage(ex)
-		if err != nil {
+	for message := range ch {
+		if err := write(message); err != nil {
 			return err
 		}
 	}
 	for err := range errCh { // if there was no errors from workers, we don't go into this loop
 		return err
 	}
-	return nil
+	// workers waiting on the chanel could have returned without error when parent was canceled
+	return parent.Err()
+}
+
+// Flush writes buffered data to the underlying writer, if TFRecordWriter was created with BufferSize.
+// Compressed data is not flushed until Close.
+func (w *TFRecordWriter) Flush() error {
+	if w.buffered == nil {
+		return nil
+	}
+	return w.buffered.Flush()
+}
+
+// WriteMessagesOrdered reads TFExamples from reader sequentially, marshals them with numWorkers goroutines
+// and writes them in the same order as they were read, so the result is byte-identical to WriteMessages.
+func (w *TFRecordWriter) WriteMessagesOrdered(reader TFExampleReader, numWorkers int) error {
+	return writeMessagesOrdered(examplesSource(reader), numWorkers, w.Write)
+}
+
+// orderedWindowFactor - number of messages being marshalled and waiting to be written in order
+// is limited to orderedWindowFactor * numWorkers
+const orderedWindowFactor = 4
+
+// writeMessagesOrdered reads messages from read, marshals them in parallel with numWorkers goroutines
+// and passes them to write in order of reading.
+func writeMessagesOrdered(read func() (protobuf.Message, error), numWorkers int, write func([]byte) (int, error)) error {
+	cmn.Assert(numWorkers > 0)
+	p := ordered.NewProcessor(
+		func() (interface{}, error) { return read() },
+		func(_ int, item interface{}) (interface{}, bool, error) {
+			b, err := marshalOptions.Marshal(item.(protobuf.Message))
+			return b, true, err
+		},
+		numWorkers, orderedWindowFactor*numWorkers,
+	)
+	defer p.Close()
+	for {
+		b, err := p.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := write(b.([]byte)); err != nil {
+			return err
+		}
+	}
+}
+
+// Close flushes the buffer and closes compressor if TFRecordWriter was created with buffering or compression enabled.
+// It doesn't close the underlying writer.
+func (w *TFRecordWriter) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
 }
 
 // WriteExamples behaves the same as WriteMessages but operates on channel of TFExamples
@@ -191,48 +454,275 @@ func (w *TFRecordWriter) WriteExamples(ch <-chan *TFExample) error {
 }
 
 // NewTFRecordReader creates and initializes TFRecordReader with writer w and CRC checksumming method.
+// If opts are provided, TFRecordReader reads TFRecord accordingly to them. If reading TFRecord
+// requires initialization (like reading compression header), its error will be reported on the first read.
+// If TFRecord is uncompressed and r is io.Seeker or has Size() int64 method (which is expected to return
+// number of bytes left in r), lengths of records are validated against the size before reading them.
 // Returns pointer to created TFRecordReader
-func NewTFRecordReader(r io.Reader) *TFRecordReader {
-	return &TFRecordReader{r: r, c: checksum.NewCRCChecksummer()}
+func NewTFRecordReader(r io.Reader, opts ...TFRecordOptions) *TFRecordReader {
+	o := optionsFrom(opts)
+	cmn.Assert(o.MaxRecordSize >= 0 && o.DecodeWorkers >= 0)
+	return &TFRecordReader{r: r, c: checksummerFrom(o), opts: o}
+}
+
+func (r *TFRecordReader) init() error {
+	if !r.initialized {
+		r.initialized = true
+		r.frame = frameReader{c: r.c, policy: r.opts.Checksum, limits: maxLengthLimits(r.opts), reuse: true}
+		if r.opts.Compression == NoCompression {
+			r.frame.limits.end = remainingSize(r.r)
+		}
+		if r.opts.BufferSize > 0 {
+			r.r = bufio.NewReaderSize(r.r, r.opts.BufferSize)
+		}
+		r.r, r.initErr = decompressReader(r.r, r.opts.Compression, r.c)
+		r.stream = &recordStream{r: r.r}
+	}
+	return r.initErr
 }
 
 func (r *TFRecordReader) Read() (*TFExample, error) {
+	if r.opts.DecodeWorkers > 0 {
+		return r.readDecoded()
+	}
 	ex := &TFExample{}
 	return ex, r.ReadNext(ex)
 }
 
 // ReadNext reads next message from reader and stores it in provided message
 // If error occurred ReadNext terminates immediately.
-// If read bytes are not in TFRecord format ReadNext terminates with error.
+// If read bytes are not in TFRecord format ReadNext terminates with error, unless
+// TFRecordReader was created with CorruptionSkip policy.
 func (r *TFRecordReader) ReadNext(message protobuf.Message) error {
-	payloadLengthHeader := make([]byte, 12)
-	if _, err := io.ReadFull(r.r, payloadLengthHeader); err != nil {
+	r.assertNotDecoding()
+	if err := r.init(); err != nil {
 		return err
 	}
 
-	lengthChecksum := binary.LittleEndian.Uint32(payloadLengthHeader[8:12])
-	if err := r.c.Verify(payloadLengthHeader[:8], lengthChecksum); err != nil {
-		return err
+	for {
+		start := r.offset
+		payload, err := r.readRecord()
+		if err != nil {
+			return err
+		}
+
+		// TODO: think how we should unmarshal message based on given MessageDescriptor
+		err = protobuf.Unmarshal(payload, message)
+		if err == nil {
+			return nil
+		}
+		err = &UnmarshalError{Offset: start, Index: r.index - 1, Err: err}
+		if r.opts.Corruption != CorruptionSkip {
+			return err
+		}
+		r.reportCorruption(TFRecordCorruption{Offset: start, Length: r.offset - start, Index: r.index - 1, Err: err})
+		protobuf.Reset(message)
 	}
+}
 
-	payloadLength := binary.LittleEndian.Uint64(payloadLengthHeader[0:8])
-	payload := make([]byte, payloadLength)
-	if _, err := io.ReadFull(r.r, payload); err != nil {
-		return err
+// ReadRecord reads next record and returns a copy of its payload without unmarshalling it.
+// Checksums of the record are verified. See also ReadBorrowed.
+func (r *TFRecordReader) ReadRecord() ([]byte, error) {
+	payload, err := r.ReadBorrowed()
+	if err != nil {
+		return nil, err
 	}
+	return append(make([]byte, 0, len(payload)), payload...), nil
+}
 
-	payloadChecksumBytes := make([]byte, 4)
-	if _, err := io.ReadFull(r.r, payloadChecksumBytes); err != nil {
-		return err
+// ReadBorrowed reads next record and returns its payload without unmarshalling it. The payload is borrowed:
+// it's owned by TFRecordReader, it's valid only until the next read from TFRecordReader and it must not be modified.
+// ReadBorrowed allows to process records without any allocations.
+func (r *TFRecordReader) ReadBorrowed() ([]byte, error) {
+	r.assertNotDecoding()
+	if err := r.init(); err != nil {
+		return nil, err
 	}
+	return r.readRecord()
+}
 
-	payloadChecksum := binary.LittleEndian.Uint32(payloadChecksumBytes[0:4])
-	if err := r.c.Verify(payload, payloadChecksum); err != nil {
-		return err
+// readRecord reads next record and returns its payload. With CorruptionSkip policy, corrupted
+// records are skipped and reported.
+func (r *TFRecordReader) readRecord() ([]byte, error) {
+	for {
+		start := r.offset
+		payload, n, fault, err := r.frame.read(r.stream, r.offset, r.index)
+		if err == nil {
+			r.offset += n
+			r.index++
+			return payload, nil
+		}
+		if fault == noFault || r.opts.Corruption != CorruptionSkip {
+			return nil, err
+		}
+
+		corruption := TFRecordCorruption{Offset: start, Index: r.index, Err: err}
+		switch fault {
+		case payloadFault:
+			// length header is valid, so the next record starts right after the corrupted one
+			corruption.Length = n
+			r.index++
+		case headerFault:
+			var resyncErr error
+			corruption.Length, resyncErr = r.resync(r.frame.header[:])
+			if resyncErr != nil && resyncErr != io.EOF {
+				return nil, resyncErr
+			}
+		case truncatedFault:
+			corruption.Length = err.(*TruncatedRecordError).Read
+		}
+		r.offset += corruption.Length
+		r.skipCorruption(corruption)
+		if fault == truncatedFault {
+			return nil, io.EOF
+		}
+	}
+}
+
+type (
+	// recordLimits describes constraints on records read by frameReader
+	recordLimits struct {
+		maxLength uint64 // maximum length of payload
+		end       int64  // offset of the end of TFRecord, negative if unknown
+	}
+
+	// frameReader reads TFRecord records and verifies their checksums with c, according to policy
+	frameReader struct {
+		c      checksum.Checksummer
+		policy ChecksumPolicy
+		limits recordLimits
+		header [headerSize]byte
+		// if reuse is set, payload buffer is reused by subsequent reads, so returned payload
+		// is valid only until the next read
+		reuse bool
+		buf   []byte
+	}
+)
+
+// maxLengthLimits returns recordLimits with maximum length of payload defined by opts
+func maxLengthLimits(opts TFRecordOptions) recordLimits {
+	limits := recordLimits{maxLength: DefaultMaxRecordSize, end: -1}
+	if opts.MaxRecordSize > 0 {
+		limits.maxLength = uint64(opts.MaxRecordSize)
+	}
+	return limits
+}
+
+// maxPayloadLength is the largest payload which can be read into memory
+const maxPayloadLength = uint64(^uint(0)>>1) - headerSize - footerSize
+
+// remainingSize returns number of bytes left in r, if it can be determined without reading r. Otherwise returns -1.
+func remainingSize(r io.Reader) int64 {
+	if seeker, ok := r.(io.Seeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return -1
+		}
+		return end - start
+	}
+	if sizer, ok := r.(interface{ Size() int64 }); ok {
+		return sizer.Size()
+	}
+	return -1
+}
+
+// read reads single TFRecord record from r. Offset and index describe position of the record in TFRecord
+// and are used only in errors. Returns record's payload, number of bytes read from r and error.
+// If error occurred because of invalid TFRecord format, fault describes which part of the record is invalid.
+func (f *frameReader) read(r io.Reader, offset, index int64) (payload []byte, n int64, fault frameFault, err error) {
+	var headerChecksummer checksum.Checksummer
+	if f.policy.verifyHeader() {
+		headerChecksummer = f.c
+	}
+	payloadLength, err := readHeader(r, headerChecksummer, f.header[:], offset, index, f.limits.maxLength)
+	switch e := err.(type) {
+	case nil:
+		n += headerSize
+	case *TruncatedRecordError:
+		return nil, e.Read, truncatedFault, err
+	case *HeaderChecksumError, *OversizedRecordError:
+		return nil, headerSize, headerFault, err
+	default:
+		return nil, n, noFault, err
+	}
+
+	size := headerSize + int64(payloadLength) + footerSize
+	if f.limits.end >= 0 && offset+size > f.limits.end {
+		// don't even try to read the record, which is longer than what is left in TFRecord
+		return nil, n, truncatedFault, &TruncatedRecordError{Offset: offset, Index: index, Size: size, Read: f.limits.end - offset}
+	}
+
+	var read int64
+	if need := size - headerSize; int64(cap(f.buf)) < need && f.limits.end < 0 && size > streamingChunkSize {
+		// the length might be corrupted, so grow the buffer as the bytes are actually read
+		b := bytes.NewBuffer(make([]byte, 0, streamingChunkSize))
+		read, err = io.CopyN(b, r, need)
+		payload = b.Bytes()
+	} else {
+		if int64(cap(f.buf)) >= need {
+			payload = f.buf[:need]
+		} else {
+			payload = make([]byte, need)
+		}
+		var readBytes int
+		readBytes, err = io.ReadFull(r, payload)
+		read = int64(readBytes)
+	}
+	if f.reuse && cap(payload) <= maxRetainedBufferSize {
+		f.buf = payload[:0]
 	}
 
-	// TODO: think how we should unmarshal message based on given MessageDescriptor
-	return protobuf.Unmarshal(payload, message)
+	n += read
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, n, truncatedFault, &TruncatedRecordError{Offset: offset, Index: index, Size: size, Read: n}
+	}
+	if err != nil {
+		return nil, n, noFault, err
+	}
+
+	payload, footer := payload[:payloadLength], payload[payloadLength:]
+	if f.policy.verifyPayload() {
+		payloadChecksum := binary.LittleEndian.Uint32(footer)
+		if err := f.c.Verify(payload, payloadChecksum); err != nil {
+			return nil, n, payloadFault, &PayloadChecksumError{Offset: offset, Index: index, Err: err}
+		}
+	}
+
+	return payload, n, noFault, nil
+}
+
+// readHeader reads TFRecord length header from r into header buffer and verifies its checksum with c,
+// unless c is nil.
+// Offset and index describe position of the record in TFRecord and are used only in errors.
+// Returns io.EOF if r is exhausted before the header. Returns length of record's payload.
+func readHeader(r io.Reader, c checksum.Checksummer, header []byte, offset, index int64, maxLength uint64) (uint64, error) {
+	read, err := io.ReadFull(r, header)
+	if err == io.ErrUnexpectedEOF {
+		return 0, &TruncatedRecordError{Offset: offset, Index: index, Read: int64(read)}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if c != nil {
+		lengthChecksum := binary.LittleEndian.Uint32(header[8:12])
+		if err := c.Verify(header[:8], lengthChecksum); err != nil {
+			return 0, &HeaderChecksumError{Offset: offset, Index: index, Err: err}
+		}
+	}
+
+	payloadLength := binary.LittleEndian.Uint64(header[0:8])
+	if payloadLength > maxLength {
+		return 0, &OversizedRecordError{Offset: offset, Index: index, Length: payloadLength, Limit: maxLength}
+	}
+	return payloadLength, nil
 }
 
 // ReadAllExamples reads examples from TFRecord until EOF and loads them into memory
@@ -21,10 +21,18 @@ type (
 
 	CrcChecksummer struct{}
 
+	// InvalidChecksumError is returned by Checksummer.Verify when checksums don't match
 	InvalidChecksumError struct {
+		Type     string
+		Expected uint32
+		Actual   uint32
 	}
 )
 
+func (e *InvalidChecksumError) Error() string {
+	return fmt.Sprintf("invalid checksum %s; got %d, expected %d", e.Type, e.Actual, e.Expected)
+}
+
 func (CrcChecksummer) Get(p []byte) uint32 {
 	crc := crc32.Checksum(p, crc32Table)
 	return ((crc >> 15) | (crc << 17)) + tfRecordCRCMask
@@ -32,8 +40,8 @@ func (CrcChecksummer) Get(p []byte) uint32 {
 
 func (c CrcChecksummer) Verify(p []byte, expected uint32) error {
 	actual := c.Get(p)
-	if c.Get(p) != expected {
-		return fmt.Errorf("invalid checksum %s; got %d, expected %d", c.Type(), actual, expected)
+	if actual != expected {
+		return &InvalidChecksumError{Type: c.Type(), Expected: expected, Actual: actual}
 	}
 	return nil
 }
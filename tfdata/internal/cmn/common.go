@@ -15,6 +15,6 @@ func AssertMsg(cond bool, msg string) {
 
 func AssertNoError(err error) {
 	if err != nil {
-		panic("assertion failed with error %v" + err.Error())
+		panic("assertion failed with error " + err.Error())
 	}
 }
@@ -0,0 +1,9 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package cmn
+
+// IsZlibHeader checks compression method, window size and header checksum of zlib stream, see RFC 1950:
+// CM = 8 (deflate), CINFO <= 7 and CMF*256 + FLG is a multiple of 31.
+func IsZlibHeader(header []byte) bool {
+	return len(header) >= 2 && header[0]&0x0f == 8 && header[0]>>4 <= 7 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
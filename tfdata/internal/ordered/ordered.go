@@ -0,0 +1,156 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+// Package ordered provides parallel processing of a stream of items which preserves their order.
+package ordered
+
+import (
+	"io"
+	"sync"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/cmn"
+)
+
+type (
+	// ReadFunc returns the next item of the stream or io.EOF if the stream is exhausted.
+	// It's never called concurrently.
+	ReadFunc func() (interface{}, error)
+
+	// ProcessFunc processes item in a goroutine identified by worker. If ok is false,
+	// the item is dropped from the stream.
+	ProcessFunc func(worker int, item interface{}) (result interface{}, ok bool, err error)
+
+	// Processor reads items sequentially, processes them with multiple goroutines and makes results
+	// available with Next in the same order as items were read. At most window items are read but not
+	// yet returned by Next, so memory usage is bounded even if processing of a single item takes long.
+	Processor struct {
+		read    ReadFunc
+		process ProcessFunc
+		window  chan struct{}
+		done    chan struct{}
+
+		readMtx sync.Mutex
+		next    int64 // sequence number of the next item to read
+		readErr error // the first error returned by read, including io.EOF
+
+		mtx      sync.Mutex
+		cond     *sync.Cond
+		results  map[int64]*result
+		expected int64 // sequence number of the next result returned by Next
+		err      error // error returned by Next
+		closed   bool
+	}
+
+	result struct {
+		value interface{}
+		ok    bool
+		err   error
+	}
+)
+
+// NewProcessor creates Processor and starts numWorkers goroutines processing items. Processor has to be
+// closed, unless Next returned an error.
+func NewProcessor(read ReadFunc, process ProcessFunc, numWorkers, window int) *Processor {
+	cmn.Assert(numWorkers > 0)
+	cmn.Assert(window > 0)
+	p := &Processor{
+		read:    read,
+		process: process,
+		window:  make(chan struct{}, window),
+		done:    make(chan struct{}),
+		results: make(map[int64]*result, window),
+	}
+	p.cond = sync.NewCond(&p.mtx)
+	for i := 0; i < numWorkers; i++ {
+		go p.work(i)
+	}
+	return p
+}
+
+func (p *Processor) work(worker int) {
+	for {
+		select {
+		case p.window <- struct{}{}:
+		case <-p.done:
+			return
+		}
+
+		p.readMtx.Lock()
+		if p.readErr != nil || p.isStopped() {
+			p.readMtx.Unlock()
+			<-p.window
+			return
+		}
+		item, err := p.read()
+		seq := p.next
+		p.next++
+		p.readErr = err
+		p.readMtx.Unlock()
+
+		if err != nil {
+			p.deliver(seq, &result{err: err})
+			return
+		}
+		value, ok, err := p.process(worker, item)
+		p.deliver(seq, &result{value: value, ok: ok, err: err})
+	}
+}
+
+func (p *Processor) deliver(seq int64, r *result) {
+	p.mtx.Lock()
+	p.results[seq] = r
+	p.cond.Broadcast()
+	p.mtx.Unlock()
+}
+
+// Next returns the next result in order of reading items. Returns io.EOF if all of items have been processed.
+// If read or processing of an item returned error, the error is returned by Next in place of the item
+// and by all subsequent calls to Next.
+func (p *Processor) Next() (interface{}, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for p.err == nil {
+		r, ok := p.results[p.expected]
+		if !ok {
+			p.cond.Wait()
+			continue
+		}
+		delete(p.results, p.expected)
+		p.expected++
+		<-p.window
+
+		switch {
+		case r.err != nil:
+			p.err = r.err
+			p.stop()
+		case r.ok:
+			return r.value, nil
+		}
+	}
+	return nil, p.err
+}
+
+// Close stops workers. Items which are already being processed are finished, but their results are discarded.
+func (p *Processor) Close() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.err == nil {
+		p.err = io.ErrClosedPipe
+	}
+	p.stop()
+}
+
+func (p *Processor) isStopped() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Processor) stop() {
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+	}
+}
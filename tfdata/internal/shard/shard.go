@@ -0,0 +1,151 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+// Package shard provides splitting of written elements into multiple files, shared by TFRecord and TAR shard writers.
+package shard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type (
+	// Info describes a single shard: its name, number of elements and number of bytes written to the file
+	Info struct {
+		Name  string
+		Count int
+		Bytes int64
+	}
+
+	// Options defines how Writer names and rotates shards. See core.TFRecordShardOptions.
+	Options struct {
+		Pattern  string
+		MaxBytes int64
+		MaxCount int
+		Create   func(name string) (io.WriteCloser, error)
+		Rename   func(oldName, newName string) error
+	}
+
+	// Writer creates, rotates and renames shards. It's not concerned with format of the shards: elements
+	// are written by the caller to io.Writer returned by Open and registered with Add.
+	Writer struct {
+		opts   Options
+		shards []Info
+
+		// current shard
+		file    io.WriteCloser
+		counter *countingWriter
+		size    int64 // bytes of elements added to the current shard, before compression
+	}
+
+	countingWriter struct {
+		w io.Writer
+		n int64
+	}
+)
+
+// ValidatePattern returns error if pattern isn't a format of shard names with two integer verbs:
+// index of a shard and total number of shards.
+func ValidatePattern(pattern string) error {
+	if pattern == "" {
+		return errors.New("shard names pattern has to be provided")
+	}
+	name := fmt.Sprintf(pattern, 0, 0)
+	if strings.Contains(name, "%!") || name == fmt.Sprintf(pattern, 1, 0) || name == fmt.Sprintf(pattern, 0, 1) {
+		return fmt.Errorf("shard names pattern %q has to have two integer verbs: index of a shard and total number of shards", pattern)
+	}
+	return nil
+}
+
+// NewWriter creates Writer. Returns error if opts.Pattern is invalid. If opts.Create or opts.Rename
+// are not provided, os.Create and os.Rename are used.
+func NewWriter(opts Options) (*Writer, error) {
+	if err := ValidatePattern(opts.Pattern); err != nil {
+		return nil, err
+	}
+	if opts.Create == nil {
+		opts.Create = func(name string) (io.WriteCloser, error) { return os.Create(name) }
+	}
+	if opts.Rename == nil {
+		opts.Rename = os.Rename
+	}
+	return &Writer{opts: opts}, nil
+}
+
+// IsOpen returns true if there is a shard which elements are written to
+func (w *Writer) IsOpen() bool {
+	return w.file != nil
+}
+
+// ShouldRotate returns true if element of given size doesn't fit into the current shard. Shard can exceed
+// MaxBytes only if it has a single element.
+func (w *Writer) ShouldRotate(size int64) bool {
+	if !w.IsOpen() {
+		return false
+	}
+	count := w.shards[len(w.shards)-1].Count
+	if w.opts.MaxCount > 0 && count >= w.opts.MaxCount {
+		return true
+	}
+	return w.opts.MaxBytes > 0 && count > 0 && w.size+size > w.opts.MaxBytes
+}
+
+// Open creates the next shard, named with total number of shards equal to 0, and returns writer to it.
+func (w *Writer) Open() (io.Writer, error) {
+	name := fmt.Sprintf(w.opts.Pattern, len(w.shards), 0)
+	file, err := w.opts.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	w.file, w.counter, w.size = file, &countingWriter{w: file}, 0
+	w.shards = append(w.shards, Info{Name: name})
+	return w.counter, nil
+}
+
+// Add registers element of given size written to the current shard
+func (w *Writer) Add(size int64) {
+	w.size += size
+	w.shards[len(w.shards)-1].Count++
+}
+
+// CloseShard closes the current shard, if any. The caller has to flush its writer before.
+func (w *Writer) CloseShard() error {
+	if !w.IsOpen() {
+		return nil
+	}
+	err := w.file.Close()
+	w.shards[len(w.shards)-1].Bytes = w.counter.n
+	w.file, w.counter = nil, nil
+	return err
+}
+
+// Close closes the current shard and renames all of the shards to their final names
+func (w *Writer) Close() error {
+	if err := w.CloseShard(); err != nil {
+		return err
+	}
+	for i := range w.shards {
+		name := fmt.Sprintf(w.opts.Pattern, i, len(w.shards))
+		if name == w.shards[i].Name {
+			continue
+		}
+		if err := w.opts.Rename(w.shards[i].Name, name); err != nil {
+			return err
+		}
+		w.shards[i].Name = name
+	}
+	return nil
+}
+
+// Shards returns manifest of shards written so far. Names are final only after Close.
+func (w *Writer) Shards() []Info {
+	return w.shards
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
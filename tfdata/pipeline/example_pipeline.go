@@ -5,6 +5,7 @@ package pipeline
 import (
 	"os"
 
+	"github.com/NVIDIA/go-tfdata/tfdata/archive"
 	"github.com/NVIDIA/go-tfdata/tfdata/core"
 	"github.com/NVIDIA/go-tfdata/tfdata/transform"
 	"github.com/NVIDIA/go-tfdata/tfdata/transform/selection"
@@ -47,3 +48,15 @@ func Example_selectOnlyImage() {
 	pipeline.TransformSamples(transform.SampleSelections(selection.ByKey("img")))
 	pipeline.SampleToTFExample().ToTFRecord(output).Do()
 }
+
+func Example_tfRecordToTar() {
+	// read TFRecord source.tfrecord, convert to WebDataset TAR and save to dest.tar.gz
+	input, _ := os.Open("source.tfrecord")
+	output, _ := os.Create("dest.tar.gz")
+
+	pipeline := NewPipeline()
+	pipeline.FromTFRecord(input).TFExampleToSample(transform.SampleEncodings{
+		"bbox": transform.EncodingJSON,
+	}).ToTar(output, archive.TarWriterOptions{Gzip: true})
+	pipeline.Do()
+}
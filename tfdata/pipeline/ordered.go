@@ -0,0 +1,127 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package pipeline
+
+import (
+	"context"
+	"io"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/cmn"
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/ordered"
+)
+
+type (
+	// sampleFeed is core.SampleReader which returns a single core.Sample set by a worker of ordered pipeline
+	sampleFeed struct {
+		sample core.Sample
+		ready  bool
+	}
+
+	orderedTFExampleReader struct {
+		p *ordered.Processor
+	}
+
+	orderedTFSequenceExampleReader struct {
+		p *ordered.Processor
+	}
+)
+
+// Ordered makes pipeline transform core.Samples with numWorkers goroutines, while preserving the order
+// of core.Samples from TarStage in the output. Samples are read sequentially and each of them is passed through
+// separate instances of SamplesStage, Sample2TFExampleStage and TFExamplesStage of a worker. Results are
+// written in the original order, so the output is byte-identical to sequential execution of the pipeline.
+// window limits number of core.Samples being transformed or waiting to be written, by default it's 4 * numWorkers.
+// Stages have to produce at most one element from each of consumed elements: a filter, which doesn't
+// produce an element, drops it from the output. numWorkers must not be passed to TFRecordStage then.
+func (p *DefaultPipeline) Ordered(numWorkers int, window ...int) *DefaultPipeline {
+	cmn.Assert(numWorkers > 0)
+	p.orderedWorkers, p.orderedWindow = numWorkers, 4*numWorkers
+	if len(window) > 0 {
+		cmn.Assert(len(window) == 1 && window[0] > 0)
+		p.orderedWindow = window[0]
+	}
+	return p
+}
+
+func (p *DefaultPipeline) doOrdered(ctx context.Context, sReader core.SampleReader) error {
+	feeds := make([]*sampleFeed, p.orderedWorkers)
+	for i := range feeds {
+		feeds[i] = &sampleFeed{}
+	}
+	read := func() (interface{}, error) { return sReader.Read() }
+
+	if p.sample2SequenceExampleStage != nil {
+		chains := make([]core.TFSequenceExampleReader, p.orderedWorkers)
+		for i := range chains {
+			chains[i] = p.sample2SequenceExampleStage(p.workerSamplesReader(feeds[i]))
+			if p.tfSequenceExamplesStage != nil {
+				chains[i] = p.tfSequenceExamplesStage(chains[i])
+			}
+		}
+		proc := ordered.NewProcessor(read, func(worker int, item interface{}) (interface{}, bool, error) {
+			feeds[worker].set(item.(core.Sample))
+			ex, err := chains[worker].Read()
+			return ex, err != io.EOF, ignoreEOF(err)
+		}, p.orderedWorkers, p.orderedWindow)
+		defer proc.Close()
+		return p.tfSequenceRecordStage(ctx, &orderedTFSequenceExampleReader{p: proc})
+	}
+
+	chains := make([]core.TFExampleReader, p.orderedWorkers)
+	for i := range chains {
+		chains[i] = p.sample2ExampleStage(p.workerSamplesReader(feeds[i]))
+		if p.tfExamplesStage != nil {
+			chains[i] = p.tfExamplesStage(chains[i])
+		}
+	}
+	proc := ordered.NewProcessor(read, func(worker int, item interface{}) (interface{}, bool, error) {
+		feeds[worker].set(item.(core.Sample))
+		ex, err := chains[worker].Read()
+		return ex, err != io.EOF, ignoreEOF(err)
+	}, p.orderedWorkers, p.orderedWindow)
+	defer proc.Close()
+	return p.tfRecordStage(ctx, &orderedTFExampleReader{p: proc})
+}
+
+func (p *DefaultPipeline) workerSamplesReader(feed *sampleFeed) core.SampleReader {
+	if p.samplesStage != nil {
+		return p.samplesStage(feed)
+	}
+	return feed
+}
+
+func ignoreEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func (f *sampleFeed) set(sample core.Sample) {
+	f.sample, f.ready = sample, true
+}
+
+func (f *sampleFeed) Read() (core.Sample, error) {
+	if !f.ready {
+		return nil, io.EOF
+	}
+	f.ready = false
+	return f.sample, nil
+}
+
+func (r *orderedTFExampleReader) Read() (*core.TFExample, error) {
+	ex, err := r.p.Next()
+	if err != nil {
+		return nil, err
+	}
+	return ex.(*core.TFExample), nil
+}
+
+func (r *orderedTFSequenceExampleReader) Read() (*core.TFSequenceExample, error) {
+	ex, err := r.p.Next()
+	if err != nil {
+		return nil, err
+	}
+	return ex.(*core.TFSequenceExample), nil
+}
@@ -4,11 +4,15 @@
 package pipeline
 
 import (
+	"context"
+	"fmt"
 	"io"
 
 	"github.com/NVIDIA/go-tfdata/tfdata/archive"
 	"github.com/NVIDIA/go-tfdata/tfdata/core"
 	"github.com/NVIDIA/go-tfdata/tfdata/filter"
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/cmn"
+	"github.com/NVIDIA/go-tfdata/tfdata/spec"
 	"github.com/NVIDIA/go-tfdata/tfdata/transform"
 )
 
@@ -24,14 +28,44 @@ type (
 	// TFRecordStage consumes core.TFExampleReader
 	TFRecordStage func(core.TFExampleReader) error
 
+	// Sample2TFSequenceExampleStage transforms core.Sample to core.TFSequenceExample: consumes core.SampleReader
+	// and produces core.TFSequenceExampleReader
+	Sample2TFSequenceExampleStage func(core.SampleReader) core.TFSequenceExampleReader
+	// TFSequenceExamplesStage makes transformation on core.TFSequenceExample: consumes core.TFSequenceExampleReader
+	// and produces core.TFSequenceExampleReader
+	TFSequenceExamplesStage func(core.TFSequenceExampleReader) core.TFSequenceExampleReader
+	// TFSequenceRecordStage consumes core.TFSequenceExampleReader
+	TFSequenceRecordStage func(core.TFSequenceExampleReader) error
+
+	// tfRecordStageContext and tfSequenceRecordStageContext are TFRecordStage and TFSequenceRecordStage
+	// which are passed ctx of DoContext, so writing can be canceled together with reading
+	tfRecordStageContext         func(context.Context, core.TFExampleReader) error
+	tfSequenceRecordStageContext func(context.Context, core.TFSequenceExampleReader) error
+
 	// DefaultPipeline represents TAR file to TFRecord file conversion with an intermediate
-	// transformations on core.Sample and core.TFExample
+	// transformations on core.Sample and core.TFExample.
+	// If Sample2TFSequenceExampleStage is set, core.Samples are converted to core.TFSequenceExamples
+	// and TFSequence* stages are used instead of TFExample ones.
+	// If TFRecordSourceStage is set, the pipeline converts TFRecord file to TAR file instead, see FromTFRecord.
 	DefaultPipeline struct {
 		tarStage            TarStage
 		samplesStage        SamplesStage // optional stage - consumes the same type as produces
 		sample2ExampleStage Sample2TFExampleStage
 		tfExamplesStage     TFExamplesStage // optional stage - consumes the same type as produces
-		tfRecordStage       TFRecordStage
+		tfRecordStage       tfRecordStageContext
+
+		sample2SequenceExampleStage Sample2TFSequenceExampleStage
+		tfSequenceExamplesStage     TFSequenceExamplesStage // optional stage - consumes the same type as produces
+		tfSequenceRecordStage       tfSequenceRecordStageContext
+
+		tfRecordSourceStage   TFRecordSourceStage // set only if converting TFRecord to TAR
+		tfExample2SampleStage TFExample2SampleStage
+		tarWriteStage         TarWriteStage
+
+		orderedWorkers int // if greater than 0, pipeline is executed in ordered mode, see Ordered
+		orderedWindow  int
+
+		err error // invalid arguments of the stages, returned by DoContext
 	}
 )
 
@@ -51,6 +85,22 @@ func (p *DefaultPipeline) TransformTFExamples(tfs ...transform.TFExampleTransfor
 	})
 }
 
+// ValidateTFExamples adds validation of core.TFExamples against config as pipeline's TFExamplesStage.
+// Invalid TFExamples are handled according to policy, see spec.NewValidatingReader. If config isn't valid,
+// Do returns error without executing the pipeline.
+func (p *DefaultPipeline) ValidateTFExamples(config *spec.ExampleParserConfiguration, policy spec.ValidationPolicy,
+	onInvalid ...func(ex *core.TFExample, err *spec.InvalidExampleError)) *DefaultPipeline {
+	if err := config.Check(); err != nil {
+		p.err = fmt.Errorf("invalid ExampleParserConfiguration: %v", err)
+		return p
+	}
+	return p.WithTFExamplesStage(func(r core.TFExampleReader) core.TFExampleReader {
+		reader, err := spec.NewValidatingReader(r, config, policy, onInvalid...)
+		cmn.AssertNoError(err) // config has been already checked
+		return reader
+	})
+}
+
 // FilterEmptySamples adds filtering of empty core.Samples as pipeline's SamplesStage.
 func (p *DefaultPipeline) FilterEmptySamples() *DefaultPipeline {
 	return p.WithSamplesStage(filter.EmptySamples)
@@ -68,6 +118,15 @@ func (p *DefaultPipeline) FromTar(input io.Reader) *DefaultPipeline {
 	})
 }
 
+// FromTarStream adds reading core.Samples from input as input was a TAR file in which entries of each
+// of core.Samples are contiguous, like in WebDataset shards. core.Samples are read as they appear in input,
+// without buffering the whole TAR in memory, so input can be arbitrarily large. See archive.TarStreamReader.
+func (p *DefaultPipeline) FromTarStream(input io.Reader, opts ...archive.TarStreamOptions) *DefaultPipeline {
+	return p.WithTarStage(func() (core.SampleReader, error) {
+		return archive.NewTarStreamReader(input, opts...), nil
+	})
+}
+
 // FromTarGz adds reading core.Samples from input as input was a TAR GZ file.
 func (p *DefaultPipeline) FromTarGz(input io.Reader) *DefaultPipeline {
 	return p.WithTarStage(func() (core.SampleReader, error) {
@@ -75,18 +134,105 @@ func (p *DefaultPipeline) FromTarGz(input io.Reader) *DefaultPipeline {
 	})
 }
 
+// FromTarGzStream behaves the same as FromTarStream, but input is a TAR GZ file.
+func (p *DefaultPipeline) FromTarGzStream(input io.Reader, opts ...archive.TarStreamOptions) *DefaultPipeline {
+	return p.WithTarStage(func() (core.SampleReader, error) {
+		return archive.NewTarGzStreamReader(input, opts...)
+	})
+}
+
+// FromArchive adds reading core.Samples from input as input was a TAR file, uncompressed or compressed
+// with gzip, bzip2, zlib or any compression registered with archive.RegisterDecompressor.
+// Compression is detected from the beginning of input. See archive.NewReader.
+func (p *DefaultPipeline) FromArchive(input io.Reader) *DefaultPipeline {
+	return p.WithTarStage(func() (core.SampleReader, error) {
+		return archive.NewReader(input)
+	})
+}
+
+// FromArchiveStream behaves the same as FromTarStream, but input compression is detected the same as
+// in FromArchive.
+func (p *DefaultPipeline) FromArchiveStream(input io.Reader, opts ...archive.TarStreamOptions) *DefaultPipeline {
+	return p.WithTarStage(func() (core.SampleReader, error) {
+		return archive.NewStreamReader(input, opts...)
+	})
+}
+
 // Writers TFExamples to specified writer in TFRecord format
 // If numWorkers provided, all pipeline transformations will be done
 // asynchronously. It assumes that all underlying Readers are async-safe.
 // All default Readers, Transformations, Selections are async-safe.
 func (p *DefaultPipeline) ToTFRecord(w io.Writer, numWorkers ...int) *DefaultPipeline {
-	return p.WithTFRecordStage(func(reader core.TFExampleReader) error {
-		writer := core.NewTFRecordWriter(w)
+	return p.ToTFRecordWithOptions(w, core.TFRecordOptions{}, numWorkers...)
+}
+
+// ToTFRecordWithOptions behaves the same as ToTFRecord, but TFRecord is written accordingly to opts,
+// for example compressed with GZIP or ZLIB.
+// If pipeline converts core.Samples to core.TFSequenceExamples, TFSequenceExamples are written.
+func (p *DefaultPipeline) ToTFRecordWithOptions(w io.Writer, opts core.TFRecordOptions, numWorkers ...int) *DefaultPipeline {
+	p.tfSequenceRecordStage = func(ctx context.Context, reader core.TFSequenceExampleReader) error {
+		var (
+			writer = core.NewTFRecordWriter(w, opts)
+			err    error
+		)
 		if len(numWorkers) > 0 {
-			return writer.WriteMessagesAsync(reader, numWorkers[0])
+			err = writer.WriteSequenceMessagesAsyncContext(ctx, reader, numWorkers[0])
+		} else {
+			err = writer.WriteSequenceMessages(reader)
 		}
-		return writer.WriteMessages(reader)
-	})
+		if err != nil {
+			return err
+		}
+		return writer.Close()
+	}
+	p.tfRecordStage = func(ctx context.Context, reader core.TFExampleReader) error {
+		var (
+			writer = core.NewTFRecordWriter(w, opts)
+			err    error
+		)
+		if len(numWorkers) > 0 {
+			err = writer.WriteMessagesAsyncContext(ctx, reader, numWorkers[0])
+		} else {
+			err = writer.WriteMessages(reader)
+		}
+		if err != nil {
+			return err
+		}
+		return writer.Close()
+	}
+	return p
+}
+
+// ToTFRecordShards writes TFExamples to multiple TFRecord files with w, which rotates files according
+// to its options. Manifest of written shards is available with w.Shards() after the pipeline is done.
+// numWorkers has the same meaning as in ToTFRecord.
+// If pipeline converts core.Samples to core.TFSequenceExamples, TFSequenceExamples are written.
+func (p *DefaultPipeline) ToTFRecordShards(w *core.TFRecordShardWriter, numWorkers ...int) *DefaultPipeline {
+	p.tfSequenceRecordStage = func(ctx context.Context, reader core.TFSequenceExampleReader) error {
+		var err error
+		if len(numWorkers) > 0 {
+			err = w.WriteSequenceMessagesAsyncContext(ctx, reader, numWorkers[0])
+		} else {
+			err = w.WriteSequenceMessages(reader)
+		}
+		if err != nil {
+			return err
+		}
+		return w.Close()
+	}
+	p.tfRecordStage = func(ctx context.Context, reader core.TFExampleReader) error {
+		var err error
+		if len(numWorkers) > 0 {
+			err = w.WriteMessagesAsyncContext(ctx, reader, numWorkers[0])
+		} else {
+			err = w.WriteMessages(reader)
+		}
+		if err != nil {
+			return err
+		}
+		return w.Close()
+	}
+	return p
 }
 
 // Converts Samples to TFExamples. TypesMap defines what are actual sample types.
@@ -100,18 +246,60 @@ func (p *DefaultPipeline) SampleToTFExample(m ...core.TypesMap) *DefaultPipeline
 	})
 }
 
+// SampleToTFSequenceExample converts Samples to TFSequenceExamples. Sample entries selected by lists
+// become steps of feature lists, remaining entries become context features and are converted according to m,
+// the same as in SampleToTFExample. Overrides SampleToTFExample.
+func (p *DefaultPipeline) SampleToTFSequenceExample(lists transform.SequenceFeatureLists, m ...core.TypesMap) *DefaultPipeline {
+	return p.WithSample2TFSequenceExampleStage(func(sr core.SampleReader) core.TFSequenceExampleReader {
+		return transform.SamplesToTFSequenceExample(sr, lists, m...)
+	})
+}
+
 // Do executes pipeline based on specified stages.
 func (p *DefaultPipeline) Do() error {
+	return p.DoContext(context.Background())
+}
+
+// DoContext executes pipeline based on specified stages, until ctx is done. If ctx is canceled or times out,
+// reading core.Samples from TarStage and asynchronous writing of ToTFRecord and ToTFRecordShards
+// are interrupted and ctx.Err() is returned. If core.SampleReader produced
+// by TarStage implements io.Closer, it's closed when DoContext returns, so no background reading is left behind.
+func (p *DefaultPipeline) DoContext(ctx context.Context) error {
+	if p.err != nil {
+		return p.err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.tfRecordSourceStage != nil {
+		return p.doToTar(ctx)
+	}
 	// prepare pipeline
-	sReader, err := p.tarStage()
+	tarReader, err := p.tarStage()
 	if err != nil {
 		return err
 	}
+	if closer, ok := tarReader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	sReader := core.SampleReaderWithContext(ctx, core.NewSampleReaderContext(tarReader))
+
+	if p.orderedWorkers > 0 {
+		return p.doOrdered(ctx, sReader)
+	}
 
 	if p.samplesStage != nil {
 		sReader = p.samplesStage(sReader)
 	}
 
+	if p.sample2SequenceExampleStage != nil {
+		seqReader := p.sample2SequenceExampleStage(sReader)
+		if p.tfSequenceExamplesStage != nil {
+			seqReader = p.tfSequenceExamplesStage(seqReader)
+		}
+		return p.tfSequenceRecordStage(ctx, seqReader)
+	}
+
 	exReader := p.sample2ExampleStage(sReader)
 
 	if p.tfExamplesStage != nil {
@@ -119,7 +307,7 @@ func (p *DefaultPipeline) Do() error {
 	}
 
 	// The whole pipeline is ready, start doing the job
-	return p.tfRecordStage(exReader)
+	return p.tfRecordStage(ctx, exReader)
 }
 
 // default setters
@@ -128,9 +316,10 @@ func NewPipeline() *DefaultPipeline {
 	return &DefaultPipeline{}
 }
 
-// WithTarStage defines TarStage of a pipeline. Overrides previous value.
+// WithTarStage defines TarStage of a pipeline. Overrides previous value and TFRecordSourceStage.
 func (p *DefaultPipeline) WithTarStage(stage TarStage) *DefaultPipeline {
 	p.tarStage = stage
+	p.tfRecordSourceStage = nil
 	return p
 }
 
@@ -148,9 +337,41 @@ func (p *DefaultPipeline) WithSamplesStage(stage SamplesStage) *DefaultPipeline
 	return p
 }
 
-// WithSample2TFExampleStage defines Sample2TFExampleStage of a pipeline. Overrides previous value.
+// WithSample2TFExampleStage defines Sample2TFExampleStage of a pipeline. Overrides previous value
+// and Sample2TFSequenceExampleStage.
 func (p *DefaultPipeline) WithSample2TFExampleStage(stage Sample2TFExampleStage) *DefaultPipeline {
 	p.sample2ExampleStage = stage
+	p.sample2SequenceExampleStage = nil
+	return p
+}
+
+// WithSample2TFSequenceExampleStage defines Sample2TFSequenceExampleStage of a pipeline. Overrides previous value
+// and Sample2TFExampleStage.
+func (p *DefaultPipeline) WithSample2TFSequenceExampleStage(stage Sample2TFSequenceExampleStage) *DefaultPipeline {
+	p.sample2SequenceExampleStage = stage
+	p.sample2ExampleStage = nil
+	return p
+}
+
+// WithTFSequenceExamplesStage defines TFSequenceExamplesStage of a pipeline. If TFSequenceExamplesStage has been
+// already set, the resulting TFSequenceExamplesStage will chain together transformations in order of setting.
+func (p *DefaultPipeline) WithTFSequenceExamplesStage(stage TFSequenceExamplesStage) *DefaultPipeline {
+	if p.tfSequenceExamplesStage != nil {
+		prevStage := p.tfSequenceExamplesStage
+		p.tfSequenceExamplesStage = func(reader core.TFSequenceExampleReader) core.TFSequenceExampleReader {
+			return stage(prevStage(reader))
+		}
+	} else {
+		p.tfSequenceExamplesStage = stage
+	}
+	return p
+}
+
+// WithTFSequenceRecordStage defines TFSequenceRecordStage of a pipeline. Overrides previous value.
+func (p *DefaultPipeline) WithTFSequenceRecordStage(stage TFSequenceRecordStage) *DefaultPipeline {
+	p.tfSequenceRecordStage = func(_ context.Context, reader core.TFSequenceExampleReader) error {
+		return stage(reader)
+	}
 	return p
 }
 
@@ -170,6 +391,8 @@ func (p *DefaultPipeline) WithTFExamplesStage(stage TFExamplesStage) *DefaultPip
 
 // WithTFRecordStage defines TFRecordStage of a pipeline. Overrides previous value.
 func (p *DefaultPipeline) WithTFRecordStage(stage TFRecordStage) *DefaultPipeline {
-	p.tfRecordStage = stage
+	p.tfRecordStage = func(_ context.Context, reader core.TFExampleReader) error {
+		return stage(reader)
+	}
 	return p
 }
@@ -0,0 +1,108 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package pipeline
+
+import (
+	"context"
+	"io"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/archive"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/cmn"
+	"github.com/NVIDIA/go-tfdata/tfdata/transform"
+)
+
+type (
+	// TFRecordSourceStage produces core.TFExampleReader. It's the first stage of TFRecord to TAR conversion.
+	TFRecordSourceStage func() (core.TFExampleReader, error)
+	// TFExample2SampleStage transforms core.TFExample to core.Sample: consumes core.TFExampleReader
+	// and produces core.SampleReader
+	TFExample2SampleStage func(core.TFExampleReader) core.SampleReader
+	// TarWriteStage consumes core.SampleReader. It's the last stage of TFRecord to TAR conversion.
+	TarWriteStage func(core.SampleReader) error
+)
+
+// FromTFRecord adds reading core.TFExamples from input as input was a TFRecord file, read accordingly
+// to opts. It makes the pipeline convert TFRecord to TAR: TFExamplesStage is executed first, then
+// TFExample2SampleStage, SamplesStage and TarWriteStage. Overrides TarStage.
+func (p *DefaultPipeline) FromTFRecord(input io.Reader, opts ...core.TFRecordOptions) *DefaultPipeline {
+	return p.WithTFRecordSourceStage(func() (core.TFExampleReader, error) {
+		return core.NewTFRecordReader(input, opts...), nil
+	})
+}
+
+// TFExampleToSample converts TFExamples to Samples, encoding int64 and float features according
+// to encodings. See transform.TFExamplesToSample. It's the default TFExample2SampleStage.
+func (p *DefaultPipeline) TFExampleToSample(encodings ...transform.SampleEncodings) *DefaultPipeline {
+	return p.WithTFExample2SampleStage(func(r core.TFExampleReader) core.SampleReader {
+		return transform.TFExamplesToSample(r, encodings...)
+	})
+}
+
+// ToTar writes core.Samples to w in TAR format, with entries of each of core.Samples stored contiguously,
+// as in WebDataset shards. If opts are provided, TAR is written accordingly to them, for example as TAR GZ.
+func (p *DefaultPipeline) ToTar(w io.Writer, opts ...archive.TarWriterOptions) *DefaultPipeline {
+	return p.ToTarShards(archive.NewTarWriter(w, opts...))
+}
+
+// ToTarShards writes core.Samples with w, which can be created with archive.NewTarShardWriter to write
+// multiple TAR files. Manifest of written shards is available with w.Shards() after the pipeline is done.
+// If the pipeline fails, w isn't closed, so shards already written keep their temporary names.
+func (p *DefaultPipeline) ToTarShards(w *archive.TarWriter) *DefaultPipeline {
+	return p.WithTarWriteStage(func(reader core.SampleReader) error {
+		if err := w.WriteSamples(reader); err != nil {
+			return err
+		}
+		w.Close()
+		return w.Err()
+	})
+}
+
+// WithTFRecordSourceStage defines TFRecordSourceStage of a pipeline. Overrides previous value and TarStage.
+func (p *DefaultPipeline) WithTFRecordSourceStage(stage TFRecordSourceStage) *DefaultPipeline {
+	p.tfRecordSourceStage = stage
+	p.tarStage = nil
+	return p
+}
+
+// WithTFExample2SampleStage defines TFExample2SampleStage of a pipeline. Overrides previous value.
+func (p *DefaultPipeline) WithTFExample2SampleStage(stage TFExample2SampleStage) *DefaultPipeline {
+	p.tfExample2SampleStage = stage
+	return p
+}
+
+// WithTarWriteStage defines TarWriteStage of a pipeline. Overrides previous value.
+func (p *DefaultPipeline) WithTarWriteStage(stage TarWriteStage) *DefaultPipeline {
+	p.tarWriteStage = stage
+	return p
+}
+
+func (p *DefaultPipeline) doToTar(ctx context.Context) error {
+	cmn.AssertMsg(p.tarWriteStage != nil, "TFRecord source requires TarWriteStage")
+	cmn.AssertMsg(p.orderedWorkers == 0, "ordered execution is not supported for TFRecord source")
+
+	exReader, err := p.tfRecordSourceStage()
+	if err != nil {
+		return err
+	}
+	if closer, ok := exReader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	exReader = core.TFExampleReaderWithContext(ctx, core.NewTFExampleReaderContext(exReader))
+
+	if p.tfExamplesStage != nil {
+		exReader = p.tfExamplesStage(exReader)
+	}
+
+	var sReader core.SampleReader
+	if p.tfExample2SampleStage != nil {
+		sReader = p.tfExample2SampleStage(exReader)
+	} else {
+		sReader = transform.TFExamplesToSample(exReader)
+	}
+
+	if p.samplesStage != nil {
+		sReader = p.samplesStage(sReader)
+	}
+	return p.tarWriteStage(sReader)
+}
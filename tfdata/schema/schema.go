@@ -0,0 +1,159 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+// Package schema provides inference of features layout from existing TFExamples.
+package schema
+
+import (
+	"io"
+	"sort"
+
+	"github.com/NVIDIA/go-tfdata/proto"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+)
+
+// Kinds of features. KindMixed means that the feature has different kinds in different TFExamples,
+// KindNone that the feature has never had a kind set.
+const (
+	KindBytes Kind = "bytes"
+	KindFloat Kind = "float"
+	KindInt64 Kind = "int64"
+	KindMixed Kind = "mixed"
+	KindNone  Kind = ""
+)
+
+type (
+	// Kind is a kind of a feature's list of values
+	Kind string
+
+	// Feature describes a single feature of TFExamples
+	Feature struct {
+		Name string `json:"name"`
+		Kind Kind   `json:"kind"`
+		// MinLength, MaxLength and TypicalLength are minimal, maximal and the most common length of
+		// the feature's list of values among TFExamples containing the feature
+		MinLength     int `json:"min_length"`
+		MaxLength     int `json:"max_length"`
+		TypicalLength int `json:"typical_length"`
+		// Count is number of TFExamples containing the feature
+		Count int64 `json:"count"`
+		// Presence is fraction of TFExamples containing the feature
+		Presence float64 `json:"presence"`
+		// FixedLength is true if the feature has the same length in all TFExamples containing it
+		FixedLength bool `json:"fixed_length"`
+	}
+
+	// Schema describes features of TFExamples. It can be serialized with encoding/json.
+	Schema struct {
+		// Examples is number of inspected TFExamples
+		Examples int64 `json:"examples"`
+		// Features are sorted by name
+		Features []Feature `json:"features"`
+	}
+
+	// Inferrer gathers statistics of TFExamples added with Add and infers Schema from them.
+	// It's not safe for concurrent use.
+	Inferrer struct {
+		examples int64
+		features map[string]*featureStats
+	}
+
+	featureStats struct {
+		kind    Kind
+		count   int64
+		lengths map[int]int64 // number of occurrences of each of the lengths
+	}
+)
+
+// Infer reads TFExamples from reader until io.EOF and infers their Schema.
+func Infer(reader core.TFExampleReader) (*Schema, error) {
+	inferrer := NewInferrer()
+	for {
+		ex, err := reader.Read()
+		if err == io.EOF {
+			return inferrer.Schema(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		inferrer.Add(ex)
+	}
+}
+
+func NewInferrer() *Inferrer {
+	return &Inferrer{features: make(map[string]*featureStats)}
+}
+
+// Add updates statistics with features of ex.
+func (i *Inferrer) Add(ex *core.TFExample) {
+	i.examples++
+	for name, feature := range ex.GetFeatures().GetFeature() {
+		stats, ok := i.features[name]
+		if !ok {
+			stats = &featureStats{kind: KindNone, lengths: make(map[int]int64)}
+			i.features[name] = stats
+		}
+		kind, length := featureKindLength(feature)
+		switch {
+		case kind == KindNone:
+		case stats.kind == KindNone:
+			stats.kind = kind
+		case stats.kind != kind:
+			stats.kind = KindMixed
+		}
+		stats.count++
+		stats.lengths[length]++
+	}
+}
+
+// Schema returns Schema of TFExamples added so far.
+func (i *Inferrer) Schema() *Schema {
+	s := &Schema{Examples: i.examples, Features: make([]Feature, 0, len(i.features))}
+	for name, stats := range i.features {
+		f := Feature{
+			Name:        name,
+			Kind:        stats.kind,
+			Count:       stats.count,
+			Presence:    float64(stats.count) / float64(i.examples),
+			FixedLength: len(stats.lengths) == 1,
+			MinLength:   -1,
+		}
+		var typicalCnt int64
+		for length, cnt := range stats.lengths {
+			if f.MinLength == -1 || length < f.MinLength {
+				f.MinLength = length
+			}
+			if length > f.MaxLength {
+				f.MaxLength = length
+			}
+			// on tie prefer shorter length, so the result doesn't depend on map iteration order
+			if cnt > typicalCnt || (cnt == typicalCnt && length < f.TypicalLength) {
+				f.TypicalLength, typicalCnt = length, cnt
+			}
+		}
+		s.Features = append(s.Features, f)
+	}
+	sort.Slice(s.Features, func(i, j int) bool { return s.Features[i].Name < s.Features[j].Name })
+	return s
+}
+
+// Feature returns description of the feature name or nil if none of TFExamples contained it.
+func (s *Schema) Feature(name string) *Feature {
+	i := sort.Search(len(s.Features), func(i int) bool { return s.Features[i].Name >= name })
+	if i < len(s.Features) && s.Features[i].Name == name {
+		return &s.Features[i]
+	}
+	return nil
+}
+
+func featureKindLength(feature *proto.Feature) (Kind, int) {
+	switch kind := feature.GetKind().(type) {
+	case *proto.Feature_BytesList:
+		return KindBytes, len(kind.BytesList.GetValue())
+	case *proto.Feature_FloatList:
+		return KindFloat, len(kind.FloatList.GetValue())
+	case *proto.Feature_Int64List:
+		return KindInt64, len(kind.Int64List.GetValue())
+	default:
+		return KindNone, 0
+	}
+}
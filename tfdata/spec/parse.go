@@ -0,0 +1,161 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package spec
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+)
+
+type (
+	// Tensor is a dense tensor of values of DType. Values are stored in row-major order in one of
+	// Floats, Int64s and Bytes, depending on DType.
+	Tensor struct {
+		DType  DType
+		Shape  []int64
+		Floats []float32
+		Int64s []int64
+		Bytes  [][]byte
+	}
+
+	// SparseTensor is a sparse tensor, like tf.sparse.SparseTensor. i-th of Values is at position Indices[i]
+	// of a dense tensor of shape DenseShape.
+	SparseTensor struct {
+		Indices    [][]int64
+		Values     *Tensor
+		DenseShape []int64
+	}
+
+	// ParsedFeatures holds features parsed according to ExampleParserConfiguration. FixedLen features
+	// are in Dense and VarLen features are in Sparse.
+	ParsedFeatures struct {
+		Dense  map[string]*Tensor
+		Sparse map[string]*SparseTensor
+	}
+)
+
+// Len returns number of values in t
+func (t *Tensor) Len() int {
+	switch t.DType {
+	case DTypeFloat:
+		return len(t.Floats)
+	case DTypeInt64:
+		return len(t.Int64s)
+	default:
+		return len(t.Bytes)
+	}
+}
+
+// ParseExample parses features of ex according to config, like tf.io.parse_single_example. Dense tensors
+// have shapes of FixedLen features, filled with DefaultValue if ex doesn't contain a feature. Sparse tensors
+// are 1-dimensional, with DenseShape equal to number of values of a feature.
+// Returns *InvalidExampleError if ex doesn't match config, see Validate.
+func ParseExample(ex *core.TFExample, config *ExampleParserConfiguration) (*ParsedFeatures, error) {
+	return parse([]*core.TFExample{ex}, config, false)
+}
+
+// ParseExamples parses features of a batch of examples according to config, like tf.io.parse_example.
+// Dense tensors have shapes of FixedLen features prefixed with len(examples). Sparse tensors are 2-dimensional:
+// index [i, j] holds j-th value of a feature in i-th TFExample and DenseShape is [len(examples), max number
+// of values of a feature]. Returns error wrapping *InvalidExampleError if any of examples doesn't match config.
+func ParseExamples(examples []*core.TFExample, config *ExampleParserConfiguration) (*ParsedFeatures, error) {
+	return parse(examples, config, true)
+}
+
+func parse(examples []*core.TFExample, config *ExampleParserConfiguration, batch bool) (*ParsedFeatures, error) {
+	if err := config.Check(); err != nil {
+		return nil, err
+	}
+	for i, ex := range examples {
+		if err := Validate(ex, config); err != nil {
+			if batch {
+				return nil, fmt.Errorf("TFExample %d: %w", i, err)
+			}
+			return nil, err
+		}
+	}
+
+	parsed := &ParsedFeatures{Dense: make(map[string]*Tensor), Sparse: make(map[string]*SparseTensor)}
+	for _, name := range config.names() {
+		fc := config.FeatureMap[name]
+		if fc.FixedLen != nil {
+			parsed.Dense[name] = parseDense(examples, name, fc.FixedLen, batch)
+		} else {
+			parsed.Sparse[name] = parseSparse(examples, name, fc.VarLen, batch)
+		}
+	}
+	return parsed, nil
+}
+
+func parseDense(examples []*core.TFExample, name string, f *FixedLenFeature, batch bool) *Tensor {
+	t := &Tensor{DType: f.DType, Shape: append([]int64(nil), f.Shape...)}
+	if batch {
+		t.Shape = append([]int64{int64(len(examples))}, t.Shape...)
+	}
+	for _, ex := range examples {
+		if ex.HasFeature(name) {
+			t.appendFeature(ex, name)
+		} else {
+			t.appendDefault(f.DefaultValue)
+		}
+	}
+	return t
+}
+
+func parseSparse(examples []*core.TFExample, name string, f *VarLenFeature, batch bool) *SparseTensor {
+	var (
+		values = &Tensor{DType: f.DType}
+		s      = &SparseTensor{Values: values}
+		maxLen int64
+	)
+	for i, ex := range examples {
+		if !ex.HasFeature(name) {
+			continue
+		}
+		before := values.Len()
+		values.appendFeature(ex, name)
+		length := int64(values.Len() - before)
+		for j := int64(0); j < length; j++ {
+			if batch {
+				s.Indices = append(s.Indices, []int64{int64(i), j})
+			} else {
+				s.Indices = append(s.Indices, []int64{j})
+			}
+		}
+		if length > maxLen {
+			maxLen = length
+		}
+	}
+	values.Shape = []int64{int64(values.Len())}
+	s.DenseShape = []int64{maxLen}
+	if batch {
+		s.DenseShape = []int64{int64(len(examples)), maxLen}
+	}
+	return s
+}
+
+// appendFeature appends values of the feature name of ex. The feature is expected to be validated,
+// so it's either of t.DType or has no values.
+func (t *Tensor) appendFeature(ex *core.TFExample, name string) {
+	feature := ex.GetFeature(name)
+	switch t.DType {
+	case DTypeFloat:
+		t.Floats = append(t.Floats, feature.GetFloatList().GetValue()...)
+	case DTypeInt64:
+		t.Int64s = append(t.Int64s, feature.GetInt64List().GetValue()...)
+	default:
+		t.Bytes = append(t.Bytes, feature.GetBytesList().GetValue()...)
+	}
+}
+
+func (t *Tensor) appendDefault(value interface{}) {
+	switch v := value.(type) {
+	case []float32:
+		t.Floats = append(t.Floats, v...)
+	case []int64:
+		t.Int64s = append(t.Int64s, v...)
+	case [][]byte:
+		t.Bytes = append(t.Bytes, v...)
+	}
+}
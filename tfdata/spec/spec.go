@@ -0,0 +1,156 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+// Package spec describes expected features of TFExamples and validates TFExamples against such description.
+//
+// ExampleParserConfiguration, FixedLenFeature and VarLenFeature mirror ExampleParserConfiguration,
+// FixedLenFeatureProto and VarLenFeatureProto messages from TensorFlow's example_parser_configuration.proto.
+// Generated code of the messages depends on TensorFlow framework protos (DataType, TensorShapeProto,
+// TensorProto) which are not part of go-tfdata, so the messages are represented with plain Go types.
+package spec
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/NVIDIA/go-tfdata/proto"
+)
+
+// DTypes supported in TFExamples. Values are the same as of tensorflow.DataType enum.
+const (
+	DTypeInvalid DType = 0
+	DTypeFloat   DType = 1
+	DTypeString  DType = 7
+	DTypeInt64   DType = 9
+)
+
+type (
+	// DType is a type of feature's values, see tensorflow.DataType
+	DType int32
+
+	// FixedLenFeature is configuration of a feature with a fixed number of values, like tf.io.FixedLenFeature.
+	FixedLenFeature struct {
+		DType DType
+		// Shape of the feature's values. Number of values is product of dimensions, empty Shape means a scalar.
+		Shape []int64
+		// DefaultValue is used when a TFExample doesn't contain the feature. It has to be []float32, []int64
+		// or [][]byte, depending on DType, with number of values matching Shape. If nil, the feature is required.
+		DefaultValue interface{}
+	}
+
+	// VarLenFeature is configuration of a feature with a variable number of values, like tf.io.VarLenFeature.
+	VarLenFeature struct {
+		DType DType
+	}
+
+	// FeatureConfiguration holds exactly one of FixedLen and VarLen
+	FeatureConfiguration struct {
+		FixedLen *FixedLenFeature
+		VarLen   *VarLenFeature
+	}
+
+	// ExampleParserConfiguration maps names of features to their configurations
+	ExampleParserConfiguration struct {
+		FeatureMap map[string]FeatureConfiguration
+	}
+)
+
+func (t DType) String() string {
+	switch t {
+	case DTypeFloat:
+		return "float"
+	case DTypeString:
+		return "string"
+	case DTypeInt64:
+		return "int64"
+	default:
+		return fmt.Sprintf("DType(%d)", int32(t))
+	}
+}
+
+// Size returns number of values of the feature, which is product of Shape dimensions.
+func (f *FixedLenFeature) Size() int64 {
+	size := int64(1)
+	for _, dim := range f.Shape {
+		size *= dim
+	}
+	return size
+}
+
+// Check verifies that c is a valid configuration: features have supported DTypes, shapes have non-negative
+// dimensions and default values match DTypes and shapes.
+func (c *ExampleParserConfiguration) Check() error {
+	for _, name := range c.names() {
+		fc := c.FeatureMap[name]
+		if (fc.FixedLen == nil) == (fc.VarLen == nil) {
+			return fmt.Errorf("feature %q: exactly one of FixedLen and VarLen has to be set", name)
+		}
+		if fc.VarLen != nil {
+			if !fc.VarLen.DType.supported() {
+				return fmt.Errorf("feature %q: unsupported dtype %s", name, fc.VarLen.DType)
+			}
+			continue
+		}
+
+		f := fc.FixedLen
+		if !f.DType.supported() {
+			return fmt.Errorf("feature %q: unsupported dtype %s", name, f.DType)
+		}
+		for _, dim := range f.Shape {
+			if dim < 0 {
+				return fmt.Errorf("feature %q: invalid shape %v", name, f.Shape)
+			}
+		}
+		if f.DefaultValue == nil {
+			continue
+		}
+		length, ok := defaultLength(f.DType, f.DefaultValue)
+		if !ok {
+			return fmt.Errorf("feature %q: default value of type %T doesn't match dtype %s", name, f.DefaultValue, f.DType)
+		}
+		if int64(length) != f.Size() {
+			return fmt.Errorf("feature %q: default value has %d values, shape %v requires %d", name, length, f.Shape, f.Size())
+		}
+	}
+	return nil
+}
+
+// names returns sorted names of configured features
+func (c *ExampleParserConfiguration) names() []string {
+	names := make([]string, 0, len(c.FeatureMap))
+	for name := range c.FeatureMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t DType) supported() bool {
+	return t == DTypeFloat || t == DTypeString || t == DTypeInt64
+}
+
+func defaultLength(t DType, value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case []float32:
+		return len(v), t == DTypeFloat
+	case []int64:
+		return len(v), t == DTypeInt64
+	case [][]byte:
+		return len(v), t == DTypeString
+	default:
+		return 0, false
+	}
+}
+
+// featureDTypeLength returns DType and number of values of feature
+func featureDTypeLength(feature *proto.Feature) (DType, int) {
+	switch kind := feature.GetKind().(type) {
+	case *proto.Feature_FloatList:
+		return DTypeFloat, len(kind.FloatList.GetValue())
+	case *proto.Feature_BytesList:
+		return DTypeString, len(kind.BytesList.GetValue())
+	case *proto.Feature_Int64List:
+		return DTypeInt64, len(kind.Int64List.GetValue())
+	default:
+		return DTypeInvalid, 0
+	}
+}
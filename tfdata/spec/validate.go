@@ -0,0 +1,137 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package spec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/cmn"
+)
+
+// ValidationPolicy defines what ValidatingReader does with TFExamples which don't match configuration.
+const (
+	// ValidationFail makes Read return InvalidExampleError. It is the default.
+	ValidationFail ValidationPolicy = iota
+	// ValidationSkip drops invalid TFExamples
+	ValidationSkip
+	// ValidationReport passes invalid TFExamples through
+	ValidationReport
+)
+
+type (
+	ValidationPolicy int
+
+	// Violation describes a single mismatch between a TFExample and configuration of its feature
+	Violation struct {
+		Feature string
+		Reason  string
+	}
+
+	// InvalidExampleError lists all of violations found in a TFExample, ordered by feature name
+	InvalidExampleError struct {
+		Violations []Violation
+	}
+
+	// ValidatingReader is core.TFExampleReader which validates TFExamples read from the underlying reader
+	ValidatingReader struct {
+		reader    core.TFExampleReader
+		config    *ExampleParserConfiguration
+		policy    ValidationPolicy
+		onInvalid func(ex *core.TFExample, err *InvalidExampleError)
+	}
+)
+
+var _ core.TFExampleReader = &ValidatingReader{}
+
+func (e *InvalidExampleError) Error() string {
+	reasons := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		reasons = append(reasons, fmt.Sprintf("feature %q: %s", v.Feature, v.Reason))
+	}
+	return "invalid TFExample: " + strings.Join(reasons, "; ")
+}
+
+// Validate checks if ex contains required features of config with expected dtypes and numbers of values.
+// Features which are not present in config are ignored. Returns *InvalidExampleError if ex is invalid.
+func Validate(ex *core.TFExample, config *ExampleParserConfiguration) error {
+	var violations []Violation
+	for _, name := range config.names() {
+		if reason := validateFeature(ex, name, config.FeatureMap[name]); reason != "" {
+			violations = append(violations, Violation{Feature: name, Reason: reason})
+		}
+	}
+	if len(violations) > 0 {
+		return &InvalidExampleError{Violations: violations}
+	}
+	return nil
+}
+
+func validateFeature(ex *core.TFExample, name string, fc FeatureConfiguration) string {
+	feature, ok := ex.GetFeatures().GetFeature()[name]
+	if !ok {
+		if fc.FixedLen != nil && fc.FixedLen.DefaultValue == nil {
+			return "required feature is missing"
+		}
+		return ""
+	}
+
+	dtype, length := featureDTypeLength(feature)
+	expected := DTypeInvalid
+	if fc.FixedLen != nil {
+		expected = fc.FixedLen.DType
+	} else if fc.VarLen != nil {
+		expected = fc.VarLen.DType
+	}
+	// feature without values set has no dtype, it's treated as an empty list of any dtype
+	if dtype != DTypeInvalid && dtype != expected {
+		return fmt.Sprintf("expected dtype %s, got %s", expected, dtype)
+	}
+	if fc.FixedLen != nil && int64(length) != fc.FixedLen.Size() {
+		return fmt.Sprintf("expected %d values for shape %v, got %d", fc.FixedLen.Size(), fc.FixedLen.Shape, length)
+	}
+	return ""
+}
+
+// NewValidatingReader returns ValidatingReader which validates TFExamples from reader against config and handles
+// invalid ones according to policy. If onInvalid is provided, it's called with each of invalid TFExamples;
+// at most one onInvalid can be provided. Returns error if config isn't valid, see Check.
+// ValidatingReader is async-safe if reader is async-safe, in such case onInvalid may be called concurrently.
+func NewValidatingReader(reader core.TFExampleReader, config *ExampleParserConfiguration, policy ValidationPolicy,
+	onInvalid ...func(ex *core.TFExample, err *InvalidExampleError)) (core.TFExampleReader, error) {
+	if err := config.Check(); err != nil {
+		return nil, fmt.Errorf("invalid ExampleParserConfiguration: %v", err)
+	}
+	cmn.Assert(len(onInvalid) <= 1)
+	r := &ValidatingReader{reader: reader, config: config, policy: policy}
+	if len(onInvalid) > 0 {
+		r.onInvalid = onInvalid[0]
+	}
+	return r, nil
+}
+
+func (r *ValidatingReader) Read() (*core.TFExample, error) {
+	for {
+		ex, err := r.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		err = Validate(ex, r.config)
+		if err == nil {
+			return ex, nil
+		}
+		invalid := err.(*InvalidExampleError)
+		if r.onInvalid != nil {
+			r.onInvalid(ex, invalid)
+		}
+		switch r.policy {
+		case ValidationSkip:
+			continue
+		case ValidationReport:
+			return ex, nil
+		default:
+			return nil, invalid
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package transform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/NVIDIA/go-tfdata/proto"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// SampleEncoding defines how values of int64 and float features are encoded in a Sample entry
+type SampleEncoding int
+
+const (
+	// EncodingText encodes values as decimal numbers separated with spaces, for example "3" for
+	// a class label. It's a common convention of WebDataset TARs.
+	EncodingText SampleEncoding = iota
+	// EncodingJSON encodes values as JSON array
+	EncodingJSON
+	// EncodingBinary encodes values as little-endian int64s or float32s. It's the inverse of
+	// core.FeatureType.INT64LIST, core.FeatureType.FLOAT32LIST and core.FeatureType.FLOAT32.
+	EncodingBinary
+	// EncodingVarint encodes int64 values as concatenated varints. It's the inverse of core.FeatureType.INT64.
+	// It's not supported for float features.
+	EncodingVarint
+)
+
+type (
+	// SampleEncodings maps feature name to encoding of its values. Features not present in SampleEncodings
+	// are encoded with EncodingText.
+	SampleEncodings map[string]SampleEncoding
+
+	// TFExamplesToSamplesTransformer converts TFExamples to Samples
+	TFExamplesToSamplesTransformer struct {
+		reader    core.TFExampleReader
+		encodings SampleEncodings
+		cnt       uint64 // number of Samples without core.KeyEntry so far
+	}
+)
+
+var (
+	_ core.SampleReader = &TFExamplesToSamplesTransformer{}
+
+	entryNameReplacer = strings.NewReplacer("/", "_", ".", "_")
+)
+
+// TFExamplesToSample consumes TFExampleReader, converts each TFExample to Sample, produces SampleReader.
+// It's the inverse of SamplesToTFExample. Each of features becomes a Sample entry:
+//   - bytes feature with a single value is put as []byte, otherwise (or with EncodingJSON) it's encoded as JSON array
+//   - int64 and float features are encoded according to encodings, with EncodingText by default
+//   - core.KeyEntry is put as string. If TFExample doesn't have it, consecutive numbers are used as keys
+//
+// Sample entries become extensions of TAR entries names, so '/' and '.' in feature names are replaced with '_',
+// for example "image/encoded" feature becomes "image_encoded" entry. Features are still keyed by their original
+// names in encodings. Read returns error if two features are mapped to the same entry.
+func TFExamplesToSample(reader core.TFExampleReader, encodings ...SampleEncodings) core.SampleReader {
+	t := &TFExamplesToSamplesTransformer{reader: reader}
+	if len(encodings) > 0 {
+		t.encodings = encodings[0]
+	}
+	return t
+}
+
+func (t *TFExamplesToSamplesTransformer) Read() (core.Sample, error) {
+	ex, err := t.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	sample := core.NewSample()
+	for name, f := range ex.GetFeatures().GetFeature() {
+		if name == core.KeyEntry {
+			sample[name] = keyFromFeature(f)
+			continue
+		}
+		entry := entryName(name)
+		if _, ok := sample[entry]; ok || entry == core.KeyEntry {
+			return nil, fmt.Errorf("feature %s: sample entry %s already exists", name, entry)
+		}
+		if sample[entry], err = encodeFeature(f, t.encodings[name]); err != nil {
+			return nil, fmt.Errorf("feature %s: %w", name, err)
+		}
+	}
+	if _, ok := sample[core.KeyEntry]; !ok {
+		sample[core.KeyEntry] = fmt.Sprintf("%09d", atomic.AddUint64(&t.cnt, 1)-1)
+	}
+	return sample, nil
+}
+
+// entryName returns name of Sample entry for feature name, without separators of TAR entries names
+func entryName(feature string) string {
+	return entryNameReplacer.Replace(feature)
+}
+
+// keyFromFeature returns key stored by SamplesToTFExample, which marshals string keys to JSON
+func keyFromFeature(f *proto.Feature) string {
+	values := f.GetBytesList().GetValue()
+	if len(values) == 0 {
+		return ""
+	}
+	var key string
+	if jsoniter.Unmarshal(values[0], &key) == nil {
+		return key
+	}
+	return string(values[0])
+}
+
+func encodeFeature(f *proto.Feature, encoding SampleEncoding) ([]byte, error) {
+	switch kind := f.GetKind().(type) {
+	case *proto.Feature_BytesList:
+		values := kind.BytesList.GetValue()
+		if len(values) == 1 && encoding != EncodingJSON {
+			return values[0], nil
+		}
+		return jsoniter.Marshal(values)
+	case *proto.Feature_Int64List:
+		return encodeInt64s(kind.Int64List.GetValue(), encoding)
+	case *proto.Feature_FloatList:
+		return encodeFloats(kind.FloatList.GetValue(), encoding)
+	}
+	return []byte{}, nil
+}
+
+func encodeInt64s(values []int64, encoding SampleEncoding) ([]byte, error) {
+	switch encoding {
+	case EncodingText:
+		b := make([]byte, 0, 4*len(values))
+		for i, v := range values {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			b = strconv.AppendInt(b, v, 10)
+		}
+		return b, nil
+	case EncodingJSON:
+		if values == nil {
+			values = []int64{}
+		}
+		return jsoniter.Marshal(values)
+	case EncodingBinary:
+		buf := bytes.NewBuffer(make([]byte, 0, 8*len(values)))
+		err := binary.Write(buf, binary.LittleEndian, values)
+		return buf.Bytes(), err
+	case EncodingVarint:
+		b := make([]byte, 0, binary.MaxVarintLen64*len(values))
+		tmp := make([]byte, binary.MaxVarintLen64)
+		for _, v := range values {
+			b = append(b, tmp[:binary.PutVarint(tmp, v)]...)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unknown encoding %d", encoding)
+}
+
+func encodeFloats(values []float32, encoding SampleEncoding) ([]byte, error) {
+	switch encoding {
+	case EncodingText:
+		b := make([]byte, 0, 8*len(values))
+		for i, v := range values {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			b = strconv.AppendFloat(b, float64(v), 'g', -1, 32)
+		}
+		return b, nil
+	case EncodingJSON:
+		if values == nil {
+			values = []float32{}
+		}
+		return jsoniter.Marshal(values)
+	case EncodingBinary:
+		b := make([]byte, 4*len(values))
+		for i, v := range values {
+			binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+		}
+		return b, nil
+	case EncodingVarint:
+		return nil, fmt.Errorf("varint encoding is not supported for float values")
+	}
+	return nil, fmt.Errorf("unknown encoding %d", encoding)
+}
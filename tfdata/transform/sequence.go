@@ -0,0 +1,85 @@
+// Copyright (c) 2020, NVIDIA CORPORATION. All rights reserved.
+
+package transform
+
+import (
+	"sort"
+
+	"github.com/NVIDIA/go-tfdata/proto"
+	"github.com/NVIDIA/go-tfdata/tfdata/core"
+	"github.com/NVIDIA/go-tfdata/tfdata/internal/cmn"
+	"github.com/NVIDIA/go-tfdata/tfdata/transform/selection"
+)
+
+type (
+	// SequenceFeatureList defines which Sample entries are steps of a TFSequenceExample feature list.
+	SequenceFeatureList struct {
+		// Selection selects Sample entries being steps of the feature list. Steps are ordered by
+		// entries keys, so they should be zero-padded, like "frame0001", "frame0002" etc.
+		Selection selection.Sample
+		// Type of each of the steps. If nil, steps are marshaled to JSON and stored as bytes, the same as
+		// Sample entries missing from core.TypesMap in SamplesToTFExample.
+		Type core.TFFeatureType
+	}
+
+	// SequenceFeatureLists maps feature list name to its definition
+	SequenceFeatureLists map[string]SequenceFeatureList
+
+	// SamplesToTFSequenceExamplesTransformer converts Samples to TFSequenceExamples
+	SamplesToTFSequenceExamplesTransformer struct {
+		reader   core.SampleReader
+		lists    SequenceFeatureLists
+		typesMap core.TypesMap
+	}
+)
+
+var _ core.TFSequenceExampleReader = &SamplesToTFSequenceExamplesTransformer{}
+
+// SamplesToTFSequenceExample consumes SampleReader, converts each Sample to TFSequenceExample, produces
+// TFSequenceExampleReader. Sample entries selected by lists become steps of corresponding feature lists,
+// remaining entries become context features, converted in the same way as in SamplesToTFExample.
+// It can be used to convert Samples with video frames or time series into TFSequenceExamples.
+func SamplesToTFSequenceExample(reader core.SampleReader, lists SequenceFeatureLists, types ...core.TypesMap) core.TFSequenceExampleReader {
+	t := &SamplesToTFSequenceExamplesTransformer{reader: reader, lists: lists}
+	if len(types) > 0 {
+		cmn.Assert(len(types) == 1)
+		t.typesMap = types[0]
+	}
+	return t
+}
+
+func (t *SamplesToTFSequenceExamplesTransformer) Read() (*core.TFSequenceExample, error) {
+	sample, err := t.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		seqExample = core.NewTFSequenceExample()
+		inList     = make(map[string]struct{})
+	)
+	for name, list := range t.lists {
+		keys := list.Selection.SelectSample(sample)
+		sort.Strings(keys)
+		seqExample.SetFeatureList(name, &proto.FeatureList{})
+		for _, k := range keys {
+			step := core.NewTFExample()
+			if err := addTypedEntry(step, k, sample[k], list.Type); err != nil {
+				return nil, err
+			}
+			seqExample.AddFeatureListStep(name, step.GetFeature(k))
+			inList[k] = struct{}{}
+		}
+	}
+
+	context := seqExample.ContextExample()
+	for k, v := range sample {
+		if _, ok := inList[k]; ok {
+			continue
+		}
+		if err := addTypedEntry(context, k, v, t.typesMap[k]); err != nil {
+			return nil, err
+		}
+	}
+	return seqExample, nil
+}
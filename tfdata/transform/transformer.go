@@ -118,87 +118,94 @@ func (t *SamplesToTFExamplesTransformer) Read() (*core.TFExample, error) {
 }
 
 func (t *SampleToTFExamplesTypesTransformer) Read() (*core.TFExample, error) {
-	var (
-		ty     cmn.TFFeatureType
-		sample core.Sample
-		b      []byte
-		ok     bool
-		err    error
-	)
-	sample, err = t.reader.Read()
+	sample, err := t.reader.Read()
 	if err != nil {
 		return nil, err
 	}
 
 	example := core.NewTFExample()
 	for k, v := range sample {
-		if ty, ok = t.typesMap[k]; !ok {
-			b, err := jsoniter.Marshal(v)
-			if err != nil {
-				return nil, err
-			}
-			example.AddBytes(k, b)
-			continue
+		if err := addTypedEntry(example, k, v, t.typesMap[k]); err != nil {
+			return nil, err
+		}
+	}
+	return example, nil
+}
+
+// addTypedEntry converts Sample entry (k, v) into ty type and adds it to example as feature k.
+// If ty is nil, v is marshaled to JSON and added as bytes.
+func addTypedEntry(example *core.TFExample, k string, v interface{}, ty core.TFFeatureType) error {
+	var (
+		b   []byte
+		ok  bool
+		err error
+	)
+	if ty == nil {
+		b, err = jsoniter.Marshal(v)
+		if err != nil {
+			return err
 		}
+		example.AddBytes(k, b)
+		return nil
+	}
 
-		switch ty.FeatureType() {
-		case cmn.Int64Type:
-			var i int64
-			if i, ok = v.(int64); !ok {
-				i, err = binary.ReadVarint(bytes.NewBuffer(v.([]byte)))
-				if err != nil {
-					return nil, err
-				}
+	switch ty.FeatureType() {
+	case cmn.Int64Type:
+		var i int64
+		if i, ok = v.(int64); !ok {
+			i, err = binary.ReadVarint(bytes.NewBuffer(v.([]byte)))
+			if err != nil {
+				return err
 			}
-			example.AddInt64(k, i)
-			continue
-		case cmn.Int64ListType:
-			var i []int64
-			if i, ok = v.([]int64); !ok {
-				err = binary.Read(bytes.NewBuffer(v.([]byte)), binary.LittleEndian, &i)
-				if err != nil {
-					return nil, err
-				}
+		}
+		example.AddInt64(k, i)
+		return nil
+	case cmn.Int64ListType:
+		var i []int64
+		if i, ok = v.([]int64); !ok {
+			err = binary.Read(bytes.NewBuffer(v.([]byte)), binary.LittleEndian, &i)
+			if err != nil {
+				return err
 			}
-			example.AddInt64List(k, i)
-			continue
-		case cmn.Float32Type:
-			var i float32
-			if i, ok = v.(float32); !ok {
-				bits := binary.LittleEndian.Uint32(v.([]byte))
-				i = math.Float32frombits(bits)
+		}
+		example.AddInt64List(k, i)
+		return nil
+	case cmn.Float32Type:
+		var i float32
+		if i, ok = v.(float32); !ok {
+			bits := binary.LittleEndian.Uint32(v.([]byte))
+			i = math.Float32frombits(bits)
+		}
+		example.AddFloat(k, i)
+		return nil
+	case cmn.Float32ListType:
+		var i []float32
+		if i, ok = v.([]float32); !ok {
+			var ints []uint32
+			err = binary.Read(bytes.NewBuffer(v.([]byte)), binary.LittleEndian, &ints)
+			if err != nil {
+				return err
 			}
-			example.AddFloat(k, i)
-			continue
-		case cmn.Float32ListType:
-			var i []float32
-			if i, ok = v.([]float32); !ok {
-				var ints []uint32
-				err = binary.Read(bytes.NewBuffer(v.([]byte)), binary.LittleEndian, &ints)
-				if err != nil {
-					return nil, err
-				}
-				for _, bits := range ints {
-					i = append(i, math.Float32frombits(bits))
-				}
+			for _, bits := range ints {
+				i = append(i, math.Float32frombits(bits))
 			}
-			example.AddFloatList(k, i)
-			continue
-		case cmn.BytesType:
-			example.AddBytes(k, v.([]byte))
-			continue
-		case cmn.BytesListType:
-			example.AddBytesList(k, v.([][]byte))
-			continue
 		}
+		example.AddFloatList(k, i)
+		return nil
+	case cmn.BytesType:
+		example.AddBytes(k, v.([]byte))
+		return nil
+	case cmn.BytesListType:
+		example.AddBytesList(k, v.([][]byte))
+		return nil
+	}
 
-		if b, ok = v.([]byte); !ok {
-			b, err = jsoniter.Marshal(v)
-			if err != nil {
-				return nil, err
-			}
+	if b, ok = v.([]byte); !ok {
+		b, err = jsoniter.Marshal(v)
+		if err != nil {
+			return err
 		}
-		example.AddBytes(k, b)
 	}
-	return example, nil
+	example.AddBytes(k, b)
+	return nil
 }